@@ -2,169 +2,902 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"math/rand"
+	"io"
+	"math"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/steezeburger/roulette-simulator/roulette"
 )
 
-// Bet represents a single bet in roulette
-type Bet struct {
-	Type   string
-	Value  int
-	Amount float64
+// loadSpinsFile reads the winning numbers in the CSV file at path
+func loadSpinsFile(path string) ([]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	spins, err := roulette.LoadSpinsCSV(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return spins, nil
+}
+
+// verifyRunFile replays the run saved by -json in path, from its own seed
+// unless seed is set and for as many spins as it played unless numGames is
+// positive, and checks that the result still matches. Replays are
+// deterministic, so a run that stopped early stops at the same spin again.
+func verifyRunFile(path string, strategy *roulette.Strategy, seed int64, numGames int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var expected roulette.SimulationResult
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if seed == 0 {
+		seed = expected.Seed
+	}
+	if numGames <= 0 {
+		numGames = expected.SpinsPlayed
+	}
+	return roulette.VerifyRun(strategy, seed, numGames, &expected)
+}
+
+// writeCSVFile writes the bankroll time series of result to path
+func writeCSVFile(path string, result *roulette.SimulationResult) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := roulette.WriteBankrollCSV(file, result); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeJSON writes v to w as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
 }
 
-// Strategy represents a roulette betting strategy
-type Strategy struct {
-	InitialBankroll float64
-	Bets            []Bet
+// moneyFormat is the currency and precision results are printed in, taken
+// from the strategy being run
+var moneyFormat = roulette.DefaultMoneyFormat
+
+// money formats a dollar amount the way the results print it
+func money(dollars float64) string {
+	return moneyFormat.Format(roulette.ToCents(dollars))
 }
 
-// RouletteWheel represents the roulette wheel
-type RouletteWheel struct {
-	Numbers []int
+// fineMoney formats a dollar amount to two more places than money, for
+// averages too small to show in whole cents
+func fineMoney(dollars float64) string {
+	return moneyFormat.FormatFine(dollars)
 }
 
-// NewRouletteWheel creates a new roulette wheel
-func NewRouletteWheel() *RouletteWheel {
-	numbers := make([]int, 38)
-	for i := 0; i < 36; i++ {
-		numbers[i] = i + 1
+// sharedMoneyFormat returns the money format every strategy agrees on, or
+// the default when they differ
+func sharedMoneyFormat(strategies map[string]*roulette.Strategy) roulette.MoneyFormat {
+	shared := roulette.DefaultMoneyFormat
+	first := true
+	for _, strategy := range strategies {
+		f := strategy.MoneyFormat()
+		if first {
+			shared, first = f, false
+		} else if f != shared {
+			return roulette.DefaultMoneyFormat
+		}
 	}
-	numbers[36] = 0  // Green 0
-	numbers[37] = 00 // Green 00
-	return &RouletteWheel{Numbers: numbers}
+	return shared
 }
 
-// Spin spins the roulette wheel and returns the winning number
-func (rw *RouletteWheel) Spin() int {
-	return rw.Numbers[rand.Intn(len(rw.Numbers))]
+// perRound spreads a total change over a number of spins
+func perRound(total float64, spins int) float64 {
+	if spins == 0 {
+		return 0
+	}
+	return total / float64(spins)
 }
 
-// ParseStrategy parses the DSL input and returns a Strategy
-func ParseStrategy(input string) (*Strategy, error) {
-	lines := strings.Split(input, "\n")
-	strategy := &Strategy{}
+// errNoStrategy is returned by readStrategyInput when the input holds nothing
+// but blank lines and comments, such as an empty file or an immediate Ctrl-D
+var errNoStrategy = errors.New("no strategy entered")
+
+// errNoAnswer is returned by askInt when the input ends before an answer
+var errNoAnswer = errors.New("the input ended before an answer was given")
 
-	for _, line := range lines {
+// readStrategyInput reads the strategy DSL from the file at path, or from the
+// scanner up to a "done" line when path is empty
+func readStrategyInput(scanner *bufio.Scanner, prompts io.Writer, path string) (string, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil && isBlankInput(string(data)) {
+			err = errNoStrategy
+		}
+		return string(data), err
+	}
+	fmt.Fprintln(prompts, "Enter your roulette strategy (type 'done' on a new line when finished):")
+	var input strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "done" {
+			break
+		}
+		input.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if isBlankInput(input.String()) {
+		return "", errNoStrategy
+	}
+	return input.String(), nil
+}
+
+// isBlankInput reports whether input holds nothing but blank lines and
+// comments
+func isBlankInput(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
 		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "bankroll:") {
-			bankrollStr := strings.TrimPrefix(line, "bankroll:")
-			bankroll, err := strconv.ParseFloat(strings.TrimSpace(bankrollStr), 64)
-			if err != nil {
-				return nil, fmt.Errorf("invalid bankroll: %v", err)
-			}
-			strategy.InitialBankroll = bankroll
-		} else if strings.HasPrefix(line, "bet:") {
-			betStr := strings.TrimPrefix(line, "bet:")
-			parts := strings.Split(betStr, ",")
-			if len(parts) != 3 {
-				return nil, fmt.Errorf("invalid bet format: %s", line)
-			}
-			betType := strings.TrimSpace(parts[0])
-			betValue, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-			if err != nil {
-				return nil, fmt.Errorf("invalid bet value: %v", err)
-			}
-			betAmount, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
-			if err != nil {
-				return nil, fmt.Errorf("invalid bet amount: %v", err)
-			}
-			strategy.Bets = append(strategy.Bets, Bet{Type: betType, Value: betValue, Amount: betAmount})
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
 		}
 	}
+	return true
+}
 
-	return strategy, nil
+// printComparison runs every strategy and prints them ranked side by side,
+// spinning from seed, or from a new seed when it is zero
+func printComparison(strategies map[string]*roulette.Strategy, numGames, numRuns int, seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	comparisons := roulette.CompareStrategiesSeeded(strategies, numGames, numRuns, seed)
+	fmt.Printf("Seed: %d\n", seed)
+	fmt.Printf("%-4s %-20s %15s %12s %10s\n", "Rank", "Strategy", "Mean final", "Std dev", "Bust rate")
+	for i, c := range comparisons {
+		fmt.Printf("%-4d %-20s %15s %12s %9.1f%%\n", i+1, c.Name, money(c.Mean), money(c.StdDev), c.BustPercent)
+	}
 }
 
-// SimulateRoulette simulates roulette games using the given strategy
-func SimulateRoulette(strategy *Strategy, numGames int) float64 {
-	wheel := NewRouletteWheel()
-	bankroll := strategy.InitialBankroll
+// parseStrategyInput parses a single strategy written in the given format
+func parseStrategyInput(input, format string) (*roulette.Strategy, error) {
+	switch format {
+	case "json":
+		return roulette.ParseStrategyJSON(strings.NewReader(input))
+	case "yaml":
+		return roulette.ParseStrategyYAML(strings.NewReader(input))
+	}
+	return roulette.ParseStrategy(input)
+}
 
-	for i := 0; i < numGames; i++ {
-		winningNumber := wheel.Spin()
+// validateStrategies parses the strategy in input, which may hold several
+// named strategies when written in the DSL, and reports the warnings and
+// expected value of each
+func validateStrategies(w io.Writer, input, format string, strict bool) error {
+	strategies := make(map[string]*roulette.Strategy)
+	if format == "dsl" && roulette.HasStrategyHeaders(input) {
+		var err error
+		if strategies, err = roulette.ParseStrategies(input); err != nil {
+			return err
+		}
+	} else {
+		strategy, err := parseStrategyInput(input, format)
+		if err != nil {
+			return err
+		}
+		strategies[""] = strategy
+	}
+	if err := validateAll(strategies, strict); err != nil {
+		return err
+	}
 
-		for _, bet := range strategy.Bets {
-			if bankroll < bet.Amount {
-				continue // Skip this bet if we don't have enough money
-			}
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		strategy := strategies[name]
+		moneyFormat = strategy.MoneyFormat()
+		prefix := ""
+		if name == "" {
+			name = strategy.Name
+		}
+		if name != "" {
+			prefix = name + ": "
+		}
+		fmt.Fprintf(w, "%sOK, %d bets on the %s wheel\n", prefix, len(strategy.Bets), strategy.Wheel)
+		if strategy.Description != "" {
+			fmt.Fprintf(w, "%s%s\n", prefix, strategy.Description)
+		}
+		for _, warning := range strategy.Warnings() {
+			fmt.Fprintf(w, "%sWarning: %s\n", prefix, warning)
+		}
+		fmt.Fprintf(w, "%sExpected value per round: %s\n", prefix, fineMoney(strategy.ExpectedValuePerRound()))
+		printCoverage(w, prefix, strategy)
+		printKelly(w, prefix, strategy)
+	}
+	return nil
+}
 
-			bankroll -= bet.Amount
+// printStrategyInfo prints the strategy's name and description, if it has them
+func printStrategyInfo(w io.Writer, strategy *roulette.Strategy) {
+	if strategy.Name != "" {
+		fmt.Fprintf(w, "Strategy: %s\n", strategy.Name)
+	}
+	if strategy.Description != "" {
+		fmt.Fprintf(w, "Description: %s\n", strategy.Description)
+	}
+}
 
-			switch bet.Type {
-			case "number":
-				if bet.Value == winningNumber {
-					bankroll += bet.Amount * 36
-				}
-			case "even":
-				if winningNumber%2 == 0 && winningNumber != 0 {
-					bankroll += bet.Amount * 2
-				}
-			case "odd":
-				if winningNumber%2 != 0 && winningNumber != 0 {
-					bankroll += bet.Amount * 2
-				}
-			case "red":
-				redNumbers := []int{1, 3, 5, 7, 9, 12, 14, 16, 18, 19, 21, 23, 25, 27, 30, 32, 34, 36}
-				if contains(redNumbers, winningNumber) {
-					bankroll += bet.Amount * 2
-				}
-			case "black":
-				blackNumbers := []int{2, 4, 6, 8, 10, 11, 13, 15, 17, 20, 22, 24, 26, 28, 29, 31, 33, 35}
-				if contains(blackNumbers, winningNumber) {
-					bankroll += bet.Amount * 2
-				}
+// printHouseProfit reports the result from the casino's side of the table,
+// along with where its bankroll ended up when the strategy gives one
+func printHouseProfit(w io.Writer, strategy *roulette.Strategy, houseProfit float64) {
+	fmt.Fprintf(w, "House profit: %s\n", money(houseProfit))
+	if strategy.HouseBankroll > 0 {
+		fmt.Fprintf(w, "House bankroll: %s to %s\n", money(strategy.HouseBankroll), money(strategy.HouseBankroll+houseProfit))
+	}
+}
+
+// printCoverage prints how many pockets of the strategy's wheel its bets cover
+func printCoverage(w io.Writer, prefix string, strategy *roulette.Strategy) {
+	covered, total := strategy.Coverage(strategy.Wheel)
+	fmt.Fprintf(w, "%sCoverage: %d of %d pockets (%.1f%%)\n", prefix, covered, total, 100*float64(covered)/float64(total))
+}
+
+// printKelly notes the Kelly criterion's stake for each kind of bet in the
+// strategy, which is only positive when a payout override gives the player
+// the edge
+func printKelly(w io.Writer, prefix string, strategy *roulette.Strategy) {
+	seen := make(map[string]bool)
+	for _, bet := range strategy.Bets {
+		if seen[bet.Type] {
+			continue
+		}
+		seen[bet.Type] = true
+		kelly := roulette.KellyFraction(bet.Type, strategy.Wheel, float64(strategy.Payouts[bet.Type]))
+		if kelly > 0 {
+			fmt.Fprintf(w, "%sKelly stake for %s bets: %.2f%% of the bankroll\n", prefix, bet.Type, 100*kelly)
+		} else {
+			fmt.Fprintf(w, "%sKelly stake for %s bets: none, the house has the edge (%.2f%%)\n", prefix, bet.Type, 100*kelly)
+		}
+	}
+}
+
+// validateAll runs Validate on every strategy in name order, naming the
+// strategy in the error when there is more than one
+func validateAll(strategies map[string]*roulette.Strategy, strict bool) error {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		strategies[name].Strict = strict
+		if err := strategies[name].Validate(); err != nil {
+			if name != "" {
+				return fmt.Errorf("%s: %v", name, err)
 			}
+			return err
+		}
+	}
+	return nil
+}
+
+// printHistogram draws the histogram as horizontal bars
+func printHistogram(histogram []roulette.Bucket) {
+	const width = 40
+	most := 0
+	for _, bucket := range histogram {
+		if bucket.Count > most {
+			most = bucket.Count
+		}
+	}
+	if most == 0 {
+		return
+	}
+	fmt.Println("Final bankroll distribution:")
+	for _, bucket := range histogram {
+		bar := strings.Repeat("#", bucket.Count*width/most)
+		fmt.Printf("  %10s - %10s | %-*s %d\n", money(bucket.Low), money(bucket.High), width, bar, bucket.Count)
+	}
+}
+
+// printBiasReport describes the outcome of a bias test
+func printBiasReport(w io.Writer, report roulette.BiasReport) {
+	fmt.Fprintf(w, "Bias test over %d spins: chi-square %.2f with %d degrees of freedom (5%% critical value %.2f)\n",
+		report.Spins, report.ChiSquare, report.DegreesOfFreedom, report.CriticalValue)
+	if report.Spins == 0 {
+		return
+	}
+	if report.Biased {
+		fmt.Fprintln(w, "  The spins are unlikely to come from a fair wheel")
+	} else {
+		fmt.Fprintln(w, "  No evidence of bias")
+	}
+	for _, d := range []roulette.PocketDeviation{report.MostOver, report.MostUnder} {
+		fmt.Fprintf(w, "  Pocket %s: %d hits, %.1f expected (%+.1f sd)\n",
+			roulette.PocketLabel(d.Pocket), d.Observed, d.Expected, d.Residual)
+	}
+	for _, d := range report.Flagged {
+		fmt.Fprintf(w, "  Flagged pocket %s: %d hits, %.1f expected\n", roulette.PocketLabel(d.Pocket), d.Observed, d.Expected)
+	}
+}
+
+// printPocketCounts reports the pockets that came up most and least often,
+// counting pockets that never came up as hit zero times
+func printPocketCounts(w io.Writer, counts map[int]int, wheel roulette.WheelType) {
+	pockets := roulette.NewWheel(wheel).Numbers
+	most, least := pockets[0], pockets[0]
+	for _, pocket := range pockets[1:] {
+		if counts[pocket] > counts[most] {
+			most = pocket
+		}
+		if counts[pocket] < counts[least] {
+			least = pocket
 		}
 	}
+	fmt.Fprintf(w, "Most hit pocket: %s (%d times)\n", roulette.PocketLabel(most), counts[most])
+	fmt.Fprintf(w, "Least hit pocket: %s (%d times)\n", roulette.PocketLabel(least), counts[least])
+}
 
-	return bankroll
+// progressMinGames is the shortest session main shows a progress bar for
+const progressMinGames = 1000000
+
+// printProgress redraws a progress bar on the current line
+func printProgress(w io.Writer, spinsDone, numGames int, bankroll float64) {
+	const width = 40
+	percent := 100 * spinsDone / numGames
+	bar := strings.Repeat("#", percent*width/100)
+	fmt.Fprintf(w, "\r[%-*s] %3d%% bankroll %s", width, bar, percent, money(bankroll))
 }
 
-// contains checks if a slice contains a specific value
-func contains(slice []int, val int) bool {
-	for _, item := range slice {
-		if item == val {
-			return true
+// parseSizes parses a comma-separated list of positive amounts
+func parseSizes(list string) ([]float64, error) {
+	var sizes []float64
+	for _, field := range strings.Split(list, ",") {
+		size, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || !(size > 0) || math.IsInf(size, 0) {
+			return nil, fmt.Errorf("invalid amount %q", strings.TrimSpace(field))
 		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// printSweep prints the points of a sweep as a table, one setting a row
+func printSweep(w io.Writer, label string, points []roulette.SweepPoint) {
+	fmt.Fprintf(w, "%15s %15s %10s\n", label, "Mean profit", "Ruin")
+	for _, point := range points {
+		fmt.Fprintf(w, "%15s %15s %9.1f%%\n", money(point.Size), money(point.MeanProfit), 100*point.RuinProbability)
+	}
+}
+
+// describeOutcome explains in words why a run ended
+func describeOutcome(outcome roulette.Outcome, strategy *roulette.Strategy) string {
+	switch outcome {
+	case roulette.CompletedAllSpins:
+		return "Played every game"
+	case roulette.ReachedTakeProfit:
+		return "Reached the take-profit and walked away"
+	case roulette.HitStopLoss:
+		return "Hit the stop-loss and walked away"
+	case roulette.HitLossStreakLimit:
+		return fmt.Sprintf("Stopped after losing %d spins in a row", strategy.MaxLossStreak)
+	case roulette.WentBust:
+		return "Went bust and stopped playing"
+	case roulette.HitSpinLimit:
+		return fmt.Sprintf("Stopped at the limit of %d spins", roulette.DefaultMaxSpins)
+	case roulette.Interrupted:
+		return "Interrupted"
+	}
+	return outcome.String()
+}
+
+// flagSet reports whether the named flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// askInt prints prompt and reads a whole number from the scanner. Every
+// count main asks for is a count of something, so negatives are refused.
+func askInt(scanner *bufio.Scanner, prompts io.Writer, prompt string) (int, error) {
+	fmt.Fprint(prompts, prompt)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return 0, err
+		}
+		fmt.Fprintln(prompts)
+		return 0, errNoAnswer
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%d is negative", n)
 	}
-	return false
+	return n, nil
 }
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
+	jsonOutput := flag.Bool("json", false, "print the results as JSON")
+	csvPath := flag.String("csv", "", "write the bankroll after every spin to this CSV file")
+	spinLogPath := flag.String("spinlog", "", "stream every spin to this file in the compact binary spin log format")
+	strategyPath := flag.String("strategy", "", "read the strategy from this file and run without prompting")
+	numGames := flag.Int("games", 100, "number of games to simulate when -strategy is set")
+	numRuns := flag.Int("runs", 1, "number of runs to simulate when -strategy is set")
+	showSpins := flag.Int("show", 0, "number of spins to show from the log when -strategy is set")
+	validateOnly := flag.Bool("validate", false, "check the strategy and report on it without simulating")
+	spinsPath := flag.String("spins", "", "replay the winning numbers in this CSV file instead of spinning")
+	biasCheck := flag.Bool("bias", false, "test the spins given with -spins for wheel bias")
+	showPockets := flag.Bool("pockets", false, "report the most and least hit pockets")
+	seed := flag.Int64("seed", 0, "seed for the wheel, to repeat a run from its printed seed (0 picks one at random)")
+	sweepSizes := flag.String("sweep", "", "rerun the strategy with each of these comma-separated base bet sizes, such as 5,10,20")
+	sweepBankrolls := flag.String("bankrolls", "", "rerun the strategy from each of these comma-separated starting bankrolls, such as 100,500,1000")
+	numSessions := flag.Int("sessions", 0, "play this many visits of -games spins each, starting every visit with the full bankroll")
+	strict := flag.Bool("strict", false, "treat a first round that costs more than the bankroll as an error")
+	verifyPath := flag.String("verify", "", "check that the run matches the JSON result saved in this file by -json")
+	repl := flag.Bool("repl", false, "keep the strategy loaded and tune it with commands such as run and set")
+	format := flag.String("format", "dsl", "format the strategy is written in: dsl, json or yaml")
+	flag.Parse()
+	if *format != "dsl" && *format != "json" && *format != "yaml" {
+		fmt.Fprintf(os.Stderr, "Unknown strategy format: %s\n", *format)
+		os.Exit(2)
+	}
+	var sizes []float64
+	if *sweepSizes != "" {
+		var err error
+		if sizes, err = parseSizes(*sweepSizes); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid bet sizes to sweep: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var bankrolls []float64
+	if *sweepBankrolls != "" {
+		var err error
+		if bankrolls, err = parseSizes(*sweepBankrolls); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid bankrolls to sweep: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *spinLogPath != "" && (*csvPath != "" || *showSpins > 0) {
+		fmt.Println("-spinlog can't be combined with -csv or -show")
+		os.Exit(2)
+	}
+	if *numGames < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid number of games: %d is negative\n", *numGames)
+		os.Exit(2)
+	}
+
+	// Keep prompts out of the way of JSON output so it can be piped
+	prompts := os.Stdout
+	if *jsonOutput {
+		prompts = os.Stderr
+	}
 
-	fmt.Println("Enter your roulette strategy (type 'done' on a new line when finished):")
-	var input strings.Builder
 	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "done" {
-			break
+	interactive := *strategyPath == ""
+	input, err := readStrategyInput(scanner, prompts, *strategyPath)
+	if errors.Is(err, errNoStrategy) {
+		if interactive {
+			fmt.Fprintln(os.Stderr, "No strategy entered: type the strategy a line at a time and finish with 'done', or read it from a file with -strategy")
+		} else {
+			fmt.Fprintf(os.Stderr, "No strategy in %s: the file is empty or holds only comments\n", *strategyPath)
 		}
-		input.WriteString(line + "\n")
+		os.Exit(2)
 	}
-
-	strategy, err := ParseStrategy(input.String())
 	if err != nil {
-		fmt.Printf("Error parsing strategy: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error reading strategy: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *validateOnly {
+		if err := validateStrategies(os.Stdout, input, *format, *strict); err != nil {
+			if !interactive {
+				err = fmt.Errorf("%s: %v", *strategyPath, err)
+			}
+			fmt.Fprintf(os.Stderr, "Invalid strategy: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	fmt.Print("Enter the number of games to simulate: ")
-	scanner.Scan()
-	numGames, err := strconv.Atoi(scanner.Text())
+	if *format == "dsl" && roulette.HasStrategyHeaders(input) {
+		strategies, err := roulette.ParseStrategies(input)
+		if err == nil {
+			err = validateAll(strategies, *strict)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing strategies: %v\n", err)
+			os.Exit(1)
+		}
+		if interactive {
+			*numGames, err = askInt(scanner, prompts, "Enter the number of games to simulate: ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid number of games: %v\n", err)
+				os.Exit(2)
+			}
+			*numRuns, err = askInt(scanner, prompts, "Enter the number of runs per strategy: ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid number of runs: %v\n", err)
+				os.Exit(2)
+			}
+		}
+		if *numRuns < 1 {
+			*numRuns = 1
+		}
+		moneyFormat = sharedMoneyFormat(strategies)
+		printComparison(strategies, *numGames, *numRuns, *seed)
+		return
+	}
+
+	strategy, err := parseStrategyInput(input, *format)
+	if err == nil {
+		strategy.Strict = *strict
+		err = strategy.Validate()
+	}
 	if err != nil {
-		fmt.Printf("Invalid number of games: %v\n", err)
+		if !interactive {
+			err = fmt.Errorf("%s: %v", *strategyPath, err)
+		}
+		fmt.Fprintf(os.Stderr, "Error parsing strategy: %v\n", err)
+		os.Exit(1)
+	}
+	moneyFormat = strategy.MoneyFormat()
+	for _, warning := range strategy.Warnings() {
+		fmt.Fprintf(prompts, "Warning: %s\n", warning)
+	}
+	if *verifyPath != "" {
+		// The saved run knows how many spins it played, so -games only
+		// overrides it when given
+		verifyGames := 0
+		if flagSet("games") {
+			verifyGames = *numGames
+		}
+		if err := verifyRunFile(*verifyPath, strategy, *seed, verifyGames); err != nil {
+			fmt.Fprintf(os.Stderr, "Verification failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("The run matches the expected result")
+		return
+	}
+	if *repl {
+		runRepl(scanner, os.Stdout, strategy, *numGames, *seed)
 		return
 	}
 
-	finalBankroll := SimulateRoulette(strategy, numGames)
-	fmt.Printf("Initial bankroll: $%.2f\n", strategy.InitialBankroll)
-	fmt.Printf("Final bankroll after %d games: $%.2f\n", numGames, finalBankroll)
-	fmt.Printf("Profit/Loss: $%.2f\n", finalBankroll-strategy.InitialBankroll)
+	if interactive && *spinsPath == "" {
+		*numGames, err = askInt(scanner, prompts, "Enter the number of games to simulate: ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid number of games: %v\n", err)
+			os.Exit(2)
+		}
+		*numRuns, err = askInt(scanner, prompts, "Enter the number of runs to simulate (1 for a single session): ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid number of runs: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if len(sizes) > 0 && *spinsPath == "" {
+		sweepSeed := *seed
+		if sweepSeed == 0 {
+			sweepSeed = time.Now().UnixNano()
+		}
+		points := roulette.SweepBetSizeSeeded(strategy, sizes, *numGames, *numRuns, sweepSeed)
+		if points == nil {
+			fmt.Println("Nothing to sweep: the strategy has no fixed-amount bets")
+			return
+		}
+		if *jsonOutput {
+			if err := writeJSON(os.Stdout, points); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		printStrategyInfo(os.Stdout, strategy)
+		fmt.Printf("Seed: %d\n", sweepSeed)
+		fmt.Printf("Base bet sizes over %d runs of %d games from a %s bankroll:\n", *numRuns, *numGames, money(strategy.InitialBankroll))
+		printSweep(os.Stdout, "Base bet", points)
+		return
+	}
+
+	if len(bankrolls) > 0 && *spinsPath == "" {
+		sweepSeed := *seed
+		if sweepSeed == 0 {
+			sweepSeed = time.Now().UnixNano()
+		}
+		points := roulette.SweepBankrollSeeded(strategy, bankrolls, *numGames, *numRuns, sweepSeed)
+		if *jsonOutput {
+			if err := writeJSON(os.Stdout, points); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		printStrategyInfo(os.Stdout, strategy)
+		fmt.Printf("Seed: %d\n", sweepSeed)
+		fmt.Printf("Starting bankrolls over %d runs of %d games:\n", *numRuns, *numGames)
+		printSweep(os.Stdout, "Bankroll", points)
+		return
+	}
+
+	if *numSessions > 0 && *spinsPath == "" {
+		var sr *roulette.SessionResult
+		if *seed != 0 {
+			sr = roulette.SimulateSessionsSeeded(strategy, *numGames, *numSessions, *seed)
+		} else {
+			sr = roulette.SimulateSessions(strategy, *numGames, *numSessions)
+		}
+		if *jsonOutput {
+			if err := writeJSON(os.Stdout, sr); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		printStrategyInfo(os.Stdout, strategy)
+		fmt.Printf("Seed: %d\n", sr.Seed)
+		fmt.Printf("Sessions of up to %d games from a %s bankroll: %d\n", *numGames, money(strategy.InitialBankroll), sr.Sessions)
+		fmt.Printf("Won/lost/busted: %d/%d/%d\n", sr.Won, sr.Lost, sr.Busted)
+		fmt.Printf("Total profit/loss: %s (%s a session)\n", money(sr.TotalProfit), money(sr.TotalProfit/float64(sr.Sessions)))
+		fmt.Printf("Worst running total: %s\n", money(sr.WorstRunning))
+		fmt.Printf("Total wagered: %s\n", money(sr.TotalWagered))
+		return
+	}
+
+	if *numRuns > 1 && *spinsPath == "" {
+		var mc *roulette.MonteCarloResult
+		if *seed != 0 {
+			mc = roulette.RunMonteCarloSeeded(strategy, *numGames, *numRuns, *seed)
+		} else {
+			mc = roulette.RunMonteCarlo(strategy, *numGames, *numRuns)
+		}
+		if *jsonOutput {
+			if err := writeJSON(os.Stdout, mc); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		printStrategyInfo(os.Stdout, strategy)
+		fmt.Printf("Seed: %d\n", mc.Seed)
+		fmt.Printf("Initial bankroll: %s\n", money(strategy.InitialBankroll))
+		fmt.Printf("Expected value per round: %s (simulated %s)\n",
+			fineMoney(strategy.ExpectedValuePerRound()), fineMoney(perRound(mc.Mean-strategy.InitialBankroll, *numGames)))
+		printCoverage(os.Stdout, "", strategy)
+		fmt.Printf("Final bankroll over %d runs of %d games:\n", mc.Runs, *numGames)
+		fmt.Printf("  Mean: %s (95%% CI %s–%s, std dev %s)\n", money(mc.Mean), money(mc.MeanCILow), money(mc.MeanCIHigh), money(mc.StdDev))
+		fmt.Printf("  Median: %s\n", money(mc.Median))
+		percentiles := make([]string, 0, 5)
+		for _, p := range []float64{5, 25, 50, 75, 95} {
+			percentiles = append(percentiles, fmt.Sprintf("%gth %s", p, money(mc.Percentile(p))))
+		}
+		fmt.Printf("  Percentiles: %s\n", strings.Join(percentiles, ", "))
+		fmt.Printf("  Min/Max: %s/%s\n", money(mc.Min), money(mc.Max))
+		fmt.Printf("Runs in profit: %.1f%%\n", mc.ProfitPercent)
+		fmt.Printf("Ruin probability: %.1f%%\n", 100*mc.RuinProbability())
+		if mc.RuinProbability() > 0 {
+			for _, quarter := range []int{1, 2, 3} {
+				games := *numGames * quarter / 4
+				fmt.Printf("  within %d games: %.1f%%\n", games, 100*mc.RuinByGames(games))
+			}
+		}
+		fmt.Printf("Return to player: %.2f%%\n", mc.RTP)
+		printHouseProfit(os.Stdout, strategy, mc.HouseProfit)
+		if len(strategy.Bets) > 1 {
+			fmt.Println("Per bet across runs:")
+			for _, c := range mc.BetContributions {
+				fmt.Printf("  %d. %s: mean net %s, std dev %s, correlation %.2f, %.0f%% of the variance\n",
+					c.Bet+1, strategy.Bets[c.Bet].Type, money(c.MeanProfit), money(c.StdDev), c.Correlation, 100*c.VarianceShare)
+			}
+		}
+		if len(mc.Outcomes) > 1 || mc.Outcomes[roulette.CompletedAllSpins] == 0 {
+			fmt.Println("Runs ended by:")
+			for outcome := roulette.CompletedAllSpins; outcome <= roulette.Interrupted; outcome++ {
+				if count := mc.Outcomes[outcome]; count > 0 {
+					fmt.Printf("  %s: %d\n", describeOutcome(outcome, strategy), count)
+				}
+			}
+		}
+		printHistogram(mc.Histogram(10))
+		return
+	}
+
+	if interactive {
+		*showSpins, err = askInt(scanner, prompts, "Enter the number of spins to show from the log (0 for none): ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid number of spins: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	opts := roulette.SimulationOptions{
+		NumGames:     *numGames,
+		Seed:         *seed,
+		CountPockets: *showPockets,
+	}
+	// The bankroll is streamed straight to the CSV file or binary spin log,
+	// so a long run doesn't have to keep its spin log, unless -show needs the
+	// log anyway
+	var spinFile *os.File
+	spinLabel := "CSV"
+	if *spinLogPath != "" {
+		opts.SpinFormat, spinLabel = "binary", "spin log"
+	}
+	if (*csvPath != "" && *showSpins == 0) || *spinLogPath != "" {
+		path := *csvPath
+		if *spinLogPath != "" {
+			path = *spinLogPath
+		}
+		spinFile, err = os.Create(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", spinLabel, err)
+			os.Exit(1)
+		}
+		defer spinFile.Close()
+		opts.SpinSink = spinFile
+	} else {
+		opts.RecordSpins = *showSpins > 0 || *csvPath != ""
+	}
+	var result *roulette.SimulationResult
+	if *spinsPath != "" {
+		spins, err := loadSpinsFile(*spinsPath)
+		if err == nil {
+			result, err = roulette.ReplayWithOptions(strategy, spins, opts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error replaying spins: %v\n", err)
+			os.Exit(1)
+		}
+		if *biasCheck {
+			printBiasReport(prompts, roulette.BiasTest(spins, strategy.Wheel))
+		}
+	} else {
+		// Ctrl-C ends a long session early but still reports on the spins
+		// played up to that point
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		if *numGames >= progressMinGames {
+			opts.ProgressEvery = *numGames / 100
+			opts.ProgressFunc = func(spinsDone int, bankroll float64) {
+				printProgress(prompts, spinsDone, *numGames, bankroll)
+			}
+		}
+		result, err = roulette.SimulateWithOptionsContext(ctx, strategy, opts)
+		interrupted := ctx.Err() != nil
+		stop()
+		if opts.ProgressFunc != nil {
+			fmt.Fprintln(prompts)
+		}
+		if interrupted {
+			fmt.Fprintf(prompts, "Interrupted after %d of %d games\n", result.SpinsPlayed, *numGames)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", spinLabel, err)
+			os.Exit(1)
+		}
+	}
+	if spinFile != nil {
+		if err := spinFile.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", spinLabel, err)
+			os.Exit(1)
+		}
+	} else if *csvPath != "" {
+		if err := writeCSVFile(*csvPath, result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *jsonOutput {
+		if err := writeJSON(os.Stdout, result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	shown := *showSpins
+	if shown > len(result.SpinLog) {
+		shown = len(result.SpinLog)
+	}
+	for _, record := range result.SpinLog[len(result.SpinLog)-shown:] {
+		label := roulette.PocketLabel(record.WinningNumber)
+		if len(record.WinningNumbers) > 0 {
+			labels := make([]string, len(record.WinningNumbers))
+			for k, n := range record.WinningNumbers {
+				labels[k] = roulette.PocketLabel(n)
+			}
+			label = strings.Join(labels, " ")
+		}
+		skipped := ""
+		if len(record.Skipped) > 0 {
+			numbers := make([]string, len(record.Skipped))
+			for i, j := range record.Skipped {
+				numbers[i] = strconv.Itoa(j + 1)
+			}
+			skipped = ", skipped bet " + strings.Join(numbers, ", ")
+		}
+		if record.Spectated {
+			skipped += ", watched"
+		}
+		fmt.Printf("Spin %d: %s, net %s, bankroll %s%s\n",
+			record.Spin, label, money(record.NetChange), money(record.Bankroll), skipped)
+	}
+	printStrategyInfo(os.Stdout, strategy)
+	if result.Seed != 0 {
+		fmt.Printf("Seed: %d\n", result.Seed)
+	}
+	fmt.Printf("Initial bankroll: %s\n", money(strategy.InitialBankroll))
+	fmt.Printf("Expected value per round: %s (simulated %s)\n",
+		fineMoney(strategy.ExpectedValuePerRound()), fineMoney(perRound(result.FinalBankroll-strategy.InitialBankroll, result.SpinsPlayed)))
+	printCoverage(os.Stdout, "", strategy)
+	fmt.Printf("Final bankroll after %d games: %s\n", result.SpinsPlayed, money(result.FinalBankroll))
+	fmt.Printf("Profit/Loss: %s\n", money(result.FinalBankroll-strategy.InitialBankroll))
+	fmt.Printf("Peak bankroll: %s\n", money(result.PeakBankroll))
+	fmt.Printf("Lowest bankroll: %s\n", money(result.MinBankroll))
+	fmt.Printf("Total wagered: %s\n", money(result.TotalWagered))
+	fmt.Printf("Return to player: %.2f%%\n", result.RTP)
+	printHouseProfit(os.Stdout, strategy, result.HouseProfit)
+	fmt.Printf("Bets won/lost: %d/%d\n", result.BetsWon, result.BetsLost)
+	fmt.Printf("Longest winning/losing streak: %d/%d spins\n", result.LongestWinStreak, result.LongestLossStreak)
+	for i, spin := range result.PhaseStarts {
+		fmt.Printf("Moved on to phase %s after spin %d\n", strategy.Phases[i+1].Name, spin)
+	}
+	if strategy.Trigger != nil {
+		fmt.Printf("Trigger fired: %d times\n", result.TriggersFired)
+	}
+	if result.SpinsSpectated > 0 {
+		fmt.Printf("Spins bet/watched: %d/%d\n", result.SpinsPlayed-result.SpinsSpectated, result.SpinsSpectated)
+	}
+	if result.BetsSkipped > 0 {
+		fmt.Printf("Bets skipped for lack of funds: %d\n", result.BetsSkipped)
+	}
+	if result.TableLimitHit > 0 {
+		fmt.Printf("Table limit hit: %d times\n", result.TableLimitHit)
+	}
+	if result.PocketCounts != nil {
+		printPocketCounts(os.Stdout, result.PocketCounts, strategy.Wheel)
+	}
+	if len(strategy.Bets) > 1 {
+		fmt.Println("Per bet:")
+		for j, bet := range strategy.Bets {
+			stats := result.PerBet[j]
+			fmt.Printf("  %d. %s: won %d/%d, wagered %s, net %s",
+				j+1, bet.Format(moneyFormat), stats.TimesWon, stats.TimesPlaced, money(stats.TotalWagered), money(stats.NetProfit))
+			if stats.TimesSkipped > 0 {
+				fmt.Printf(", skipped %d times", stats.TimesSkipped)
+			}
+			fmt.Println()
+		}
+	}
+	if result.StoppedOnBust {
+		fmt.Println("Went bust and stopped playing")
+	} else if result.WentBust {
+		fmt.Println("Went bust")
+	}
+	if result.Outcome != roulette.CompletedAllSpins && result.Outcome != roulette.WentBust {
+		fmt.Println(describeOutcome(result.Outcome, strategy))
+	}
 }
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steezeburger/roulette-simulator/roulette"
+)
+
+// TestMain runs main itself instead of the tests when runMain starts the
+// test binary again, so the tests can check exit codes
+func TestMain(m *testing.M) {
+	if os.Getenv("ROULETTE_RUN_MAIN") == "1" {
+		os.Args = append(os.Args[:1], strings.Fields(os.Getenv("ROULETTE_MAIN_ARGS"))...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the program with the given arguments and standard input and
+// returns everything it printed along with its exit code
+func runMain(t *testing.T, stdin string, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "ROULETTE_RUN_MAIN=1", "ROULETTE_MAIN_ARGS="+strings.Join(args, " "))
+	cmd.Stdin = strings.NewReader(stdin)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(out), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), 0
+}
+
+// writeFile writes content to a file in a fresh temporary directory and
+// returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateStrategies(t *testing.T) {
+	var b strings.Builder
+	err := validateStrategies(&b, "bankroll: 100\nbet: red, 0, 10\nbet: black, 0, 10\n", "dsl", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{"OK, 2 bets on the american wheel", "Warning: ", "Expected value per round: -$1.0526"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	b.Reset()
+	err = validateStrategies(&b, "bankroll: 100\nbet: number, 99, 10\n", "dsl", false)
+	if err == nil || !strings.Contains(err.Error(), "on line 2") {
+		t.Errorf("got error %v, want one naming line 2", err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("printed %q for an invalid strategy", b.String())
+	}
+}
+
+func TestValidateExitCodes(t *testing.T) {
+	good := writeFile(t, "good.txt", "bankroll: 100\nbet: red, 0, 10\n")
+	out, code := runMain(t, "", "-validate", "-strategy", good)
+	if code != 0 || !strings.Contains(out, "OK, 1 bets") {
+		t.Errorf("valid strategy exited %d with:\n%s", code, out)
+	}
+	if strings.Contains(out, "Final bankroll") {
+		t.Errorf("-validate ran a simulation:\n%s", out)
+	}
+
+	bad := writeFile(t, "bad.txt", "bankroll: 100\nbet: red, 0, -10\n")
+	if out, code := runMain(t, "", "-validate", "-strategy", bad); code == 0 {
+		t.Errorf("invalid strategy exited 0 with:\n%s", out)
+	}
+}
+
+func TestAskIntRejectsNegatives(t *testing.T) {
+	var prompts strings.Builder
+	n, err := askInt(bufio.NewScanner(strings.NewReader("250\n")), &prompts, "Games: ")
+	if err != nil || n != 250 {
+		t.Errorf("got %d, %v; want 250", n, err)
+	}
+	if prompts.String() != "Games: " {
+		t.Errorf("prompted %q", prompts.String())
+	}
+	if _, err := askInt(bufio.NewScanner(strings.NewReader("-5\n")), &prompts, "Games: "); err == nil || err.Error() != "-5 is negative" {
+		t.Errorf("got error %v, want -5 is negative", err)
+	}
+
+	good := writeFile(t, "good.txt", "bankroll: 100\nbet: red, 0, 10\n")
+	if out, code := runMain(t, "", "-strategy", good, "-games", "-5"); code != 2 || !strings.Contains(out, "Invalid number of games") {
+		t.Errorf("-games -5 exited %d with:\n%s", code, out)
+	}
+}
+
+func TestParseReplCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want replCommand
+	}{
+		{"run", replCommand{name: "run"}},
+		{"run 10000", replCommand{name: "run", games: 10000}},
+		{"  RUN   50 ", replCommand{name: "run", games: 50}},
+		{"set bet 0 amount 20", replCommand{name: "set", bet: 0, amount: 20}},
+		{"set bet 2 amount 2.5", replCommand{name: "set", bet: 2, amount: 2.5}},
+		{"seed 42", replCommand{name: "seed", seed: 42}},
+		{"seed -7", replCommand{name: "seed", seed: -7}},
+		{"show", replCommand{name: "show"}},
+		{"help", replCommand{name: "help"}},
+		{"quit", replCommand{name: "quit"}},
+		{"exit", replCommand{name: "quit"}},
+	}
+	for _, tt := range tests {
+		got, err := parseReplCommand(tt.line)
+		if err != nil {
+			t.Errorf("%q: %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{
+		"",
+		"fly",
+		"run 0",
+		"run ten",
+		"run 10 20",
+		"set bet 0 amount",
+		"set bet -1 amount 5",
+		"set bet 0 amount -5",
+		"set bet 0 amount Inf",
+		"set bets 0 amount 5",
+		"seed",
+		"seed lucky",
+		"show bets",
+		"quit now",
+	} {
+		if cmd, err := parseReplCommand(line); err == nil {
+			t.Errorf("%q parsed as %+v", line, cmd)
+		}
+	}
+}
+
+func TestRunRepl(t *testing.T) {
+	strategy, err := roulette.ParseStrategy("bankroll: 1000\nbet: red, 0, 10\nbet: number, 17, 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "seed 42\nrun 100\nset bet 0 amount 20\nset bet 5 amount 1\nrun\nbogus\nshow\nquit\nrun\n"
+	var b strings.Builder
+	runRepl(bufio.NewScanner(strings.NewReader(input)), &b, strategy, 50, 0)
+	out := b.String()
+	for _, want := range []string{
+		"Final bankroll after 100 games:",
+		"seed 42)",
+		"No bet 5: the strategy has bets 0 to 1",
+		"unknown command: bogus",
+		"Bankroll $1,000.00 on the american wheel, 100 games, seed 42",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strategy.Bets[0].Amount != 20 {
+		t.Errorf("bet 0 stakes %v, want 20", strategy.Bets[0].Amount)
+	}
+	if runs := strings.Count(out, "Final bankroll"); runs != 2 {
+		t.Errorf("ran %d times, want 2 with nothing run after quit", runs)
+	}
+}
+
+func TestVerifyRunFile(t *testing.T) {
+	strategy, err := roulette.ParseStrategy("bankroll: 500\nbet: red, 0, 5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := roulette.SimulateSeeded(strategy, 250, 8)
+	data, err := json.Marshal(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeFile(t, "run.json", string(data))
+
+	// The seed and spin count come from the saved run unless given
+	if err := verifyRunFile(path, strategy, 0, 0); err != nil {
+		t.Errorf("saved run didn't verify: %v", err)
+	}
+	if err := verifyRunFile(path, strategy, 0, 100); err == nil {
+		t.Error("verifying 100 of the saved 250 spins matched")
+	}
+	if err := verifyRunFile(path, strategy, 9, 0); err == nil {
+		t.Error("verifying with another seed matched")
+	}
+
+	strategyPath := writeFile(t, "strategy.txt", "bankroll: 500\nbet: red, 0, 5\n")
+	if out, code := runMain(t, "", "-strategy", strategyPath, "-verify", path); code != 0 || !strings.Contains(out, "The run matches") {
+		t.Errorf("-verify exited %d with:\n%s", code, out)
+	}
+	if out, code := runMain(t, "", "-strategy", strategyPath, "-verify", path, "-games", "100"); code != 1 || !strings.Contains(out, "spins_played") {
+		t.Errorf("-verify -games 100 exited %d with:\n%s", code, out)
+	}
+}
+
+func TestCurrencyOutput(t *testing.T) {
+	path := writeFile(t, "units.txt", "bankroll: 100\ncurrency: units\nprecision: 0\nbet: red, 0, 10\n")
+	out, code := runMain(t, "", "-strategy", path, "-games", "5", "-seed", "3")
+	if code != 0 {
+		t.Fatalf("exited %d with:\n%s", code, out)
+	}
+	for _, want := range []string{"Initial bankroll: 100 units", "Total wagered: 50 units"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "$") {
+		t.Errorf("output still has dollar amounts:\n%s", out)
+	}
+}
+
+func TestReadStrategyInputBlank(t *testing.T) {
+	for _, stdin := range []string{"", "\n\n", "# just a comment\n  \ndone\n", "done\nbankroll: 100\n"} {
+		_, err := readStrategyInput(bufio.NewScanner(strings.NewReader(stdin)), &strings.Builder{}, "")
+		if !errors.Is(err, errNoStrategy) {
+			t.Errorf("input %q: got error %v, want errNoStrategy", stdin, err)
+		}
+	}
+	input, err := readStrategyInput(bufio.NewScanner(strings.NewReader("bankroll: 100\nbet: red, 0, 10\ndone\n25\n")), &strings.Builder{}, "")
+	if err != nil || input != "bankroll: 100\nbet: red, 0, 10\n" {
+		t.Errorf("got %q, %v; want the lines before done", input, err)
+	}
+
+	path := writeFile(t, "blank.txt", "# nothing yet\n\n")
+	if _, err := readStrategyInput(nil, &strings.Builder{}, path); !errors.Is(err, errNoStrategy) {
+		t.Errorf("blank file: got error %v, want errNoStrategy", err)
+	}
+	if _, err := readStrategyInput(nil, &strings.Builder{}, filepath.Join(t.TempDir(), "missing.txt")); err == nil || errors.Is(err, errNoStrategy) {
+		t.Errorf("missing file: got error %v, want a read error", err)
+	}
+}
+
+func TestIsBlankInput(t *testing.T) {
+	for input, want := range map[string]bool{
+		"":                      true,
+		"\n \n\t\n":             true,
+		"# a\n   # b\n":         true,
+		"bankroll: 100\n":       false,
+		"# a\nbet: red, 0, 1\n": false,
+	} {
+		if got := isBlankInput(input); got != want {
+			t.Errorf("isBlankInput(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestAskIntAtEndOfInput(t *testing.T) {
+	var prompts strings.Builder
+	if _, err := askInt(bufio.NewScanner(strings.NewReader("")), &prompts, "Games: "); !errors.Is(err, errNoAnswer) {
+		t.Errorf("got error %v, want errNoAnswer", err)
+	}
+	if _, err := askInt(bufio.NewScanner(strings.NewReader("lots\n")), &prompts, "Games: "); err == nil || errors.Is(err, errNoAnswer) {
+		t.Errorf("got error %v, want a parse error", err)
+	}
+}
+
+func TestEmptyInputExitCodes(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		stdin  string
+		args   []string
+		code   int
+		output string
+	}{
+		{"no input", "", nil, 2, "No strategy entered"},
+		{"only comments", "# thinking\n\ndone\n", nil, 2, "No strategy entered"},
+		{"blank file", "", []string{"-strategy", writeFile(t, "blank.txt", "\n# later\n")}, 2, "No strategy in "},
+		{"invalid strategy", "bankroll: 100\nbet: purple, 0, 10\ndone\n", nil, 1, "Error parsing strategy"},
+		{"input ends before games", "bankroll: 100\nbet: red, 0, 10\ndone\n", nil, 2, "Invalid number of games: " + errNoAnswer.Error()},
+		{"bad games answer", "bankroll: 100\nbet: red, 0, 10\ndone\nlots\n", nil, 2, "Invalid number of games"},
+		{"input ends before runs", "bankroll: 100\nbet: red, 0, 10\ndone\n10\n", nil, 2, "Invalid number of runs: " + errNoAnswer.Error()},
+	} {
+		out, code := runMain(t, tt.stdin, tt.args...)
+		if code != tt.code || !strings.Contains(out, tt.output) {
+			t.Errorf("%s: exited %d with:\n%s\nwant exit %d with %q", tt.name, code, out, tt.code, tt.output)
+		}
+		if strings.Contains(out, "Final bankroll") {
+			t.Errorf("%s: ran a simulation anyway:\n%s", tt.name, out)
+		}
+	}
+}
+
+func TestFailedOutputExitsWithErrorOnStderr(t *testing.T) {
+	strategy := writeFile(t, "strategy.txt", "bankroll: 100\nbet: red, 0, 10\n")
+	missing := filepath.Join(t.TempDir(), "missing", "spins.csv")
+	for _, tt := range []struct {
+		name   string
+		args   []string
+		output string
+	}{
+		{"missing spins file", []string{"-strategy", strategy, "-spins", missing}, "Error replaying spins"},
+		{"unwritable CSV", []string{"-strategy", strategy, "-csv", missing}, "Error writing CSV"},
+	} {
+		cmd := exec.Command(os.Args[0])
+		cmd.Env = append(os.Environ(), "ROULETTE_RUN_MAIN=1", "ROULETTE_MAIN_ARGS="+strings.Join(tt.args, " "))
+		var stdout, stderr strings.Builder
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		var exitErr *exec.ExitError
+		if err := cmd.Run(); !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			t.Errorf("%s: got %v, want exit status 1", tt.name, err)
+		}
+		if !strings.Contains(stderr.String(), tt.output) || strings.Contains(stdout.String(), tt.output) {
+			t.Errorf("%s: stdout\n%s\nstderr\n%s\nwant %q on stderr only", tt.name, stdout.String(), stderr.String(), tt.output)
+		}
+	}
+}
+
+func TestCoverageOutput(t *testing.T) {
+	path := writeFile(t, "cover.txt", "bankroll: 100\nbet: red, 0, 10\nbet: number, 0, 1\n")
+	out, code := runMain(t, "", "-strategy", path, "-games", "5", "-seed", "3")
+	if code != 0 || !strings.Contains(out, "Coverage: 19 of 38 pockets (50.0%)") {
+		t.Errorf("exited %d with:\n%s\nwant the coverage line", code, out)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/steezeburger/roulette-simulator/roulette"
+)
+
+// replHelp lists the commands the REPL understands
+const replHelp = `Commands:
+  run [games]               simulate the strategy, for games spins if given
+  set bet <n> amount <x>    stake $x on bet n, counting bets from 0
+  seed <n>                  spin with seed n from now on, 0 for a new seed each run
+  show                      print the strategy and settings
+  help                      print this list
+  quit                      leave the REPL`
+
+// replCommand is one parsed line of REPL input
+type replCommand struct {
+	name   string // run, set, seed, show, help or quit
+	games  int    // Spins for run, zero to keep the current count
+	bet    int    // Index of the bet for set
+	amount float64
+	seed   int64
+}
+
+// parseReplCommand parses a line of REPL input
+func parseReplCommand(line string) (replCommand, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return replCommand{}, fmt.Errorf("no command given")
+	}
+	cmd := replCommand{name: strings.ToLower(fields[0])}
+	args := fields[1:]
+	switch cmd.name {
+	case "run":
+		if len(args) > 1 {
+			return cmd, fmt.Errorf("usage: run [games]")
+		}
+		if len(args) == 1 {
+			games, err := strconv.Atoi(args[0])
+			if err != nil || games < 1 {
+				return cmd, fmt.Errorf("invalid number of games %q", args[0])
+			}
+			cmd.games = games
+		}
+	case "set":
+		if len(args) != 4 || args[0] != "bet" || args[2] != "amount" {
+			return cmd, fmt.Errorf("usage: set bet <n> amount <x>")
+		}
+		bet, err := strconv.Atoi(args[1])
+		if err != nil || bet < 0 {
+			return cmd, fmt.Errorf("invalid bet index %q", args[1])
+		}
+		amount, err := strconv.ParseFloat(args[3], 64)
+		if err != nil || !(amount > 0) || math.IsInf(amount, 0) {
+			return cmd, fmt.Errorf("invalid bet amount %q", args[3])
+		}
+		cmd.bet, cmd.amount = bet, amount
+	case "seed":
+		if len(args) != 1 {
+			return cmd, fmt.Errorf("usage: seed <n>")
+		}
+		seed, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return cmd, fmt.Errorf("invalid seed %q", args[0])
+		}
+		cmd.seed = seed
+	case "show", "help", "quit", "exit":
+		if len(args) > 0 {
+			return cmd, fmt.Errorf("%s takes no arguments", cmd.name)
+		}
+		if cmd.name == "exit" {
+			cmd.name = "quit"
+		}
+	default:
+		return cmd, fmt.Errorf("unknown command: %s", cmd.name)
+	}
+	return cmd, nil
+}
+
+// runRepl reads commands from scanner until quit or the end of input,
+// simulating the strategy as it is tweaked
+func runRepl(scanner *bufio.Scanner, w io.Writer, strategy *roulette.Strategy, numGames int, seed int64) {
+	fmt.Fprintln(w, "Type 'help' for a list of commands")
+	for {
+		fmt.Fprint(w, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return
+		}
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		cmd, err := parseReplCommand(scanner.Text())
+		if err != nil {
+			fmt.Fprintln(w, err)
+			continue
+		}
+		switch cmd.name {
+		case "run":
+			if cmd.games > 0 {
+				numGames = cmd.games
+			}
+			result := roulette.SimulateWithOptions(strategy, roulette.SimulationOptions{NumGames: numGames, Seed: seed})
+			fmt.Fprintf(w, "Final bankroll after %d games: %s (profit/loss %s, seed %d)\n",
+				result.SpinsPlayed, money(result.FinalBankroll), money(result.FinalBankroll-strategy.InitialBankroll), result.Seed)
+			if result.Outcome != roulette.CompletedAllSpins {
+				fmt.Fprintln(w, describeOutcome(result.Outcome, strategy))
+			}
+		case "set":
+			if cmd.bet >= len(strategy.Bets) {
+				fmt.Fprintf(w, "No bet %d: the strategy has bets 0 to %d\n", cmd.bet, len(strategy.Bets)-1)
+				continue
+			}
+			strategy.Bets[cmd.bet].Amount = cmd.amount
+			strategy.Bets[cmd.bet].Percent = 0
+		case "seed":
+			seed = cmd.seed
+		case "show":
+			seeding := "a new seed each run"
+			if seed != 0 {
+				seeding = fmt.Sprintf("seed %d", seed)
+			}
+			fmt.Fprintf(w, "Bankroll %s on the %s wheel, %d games, %s\n", money(strategy.InitialBankroll), strategy.Wheel, numGames, seeding)
+			for j, bet := range strategy.Bets {
+				fmt.Fprintf(w, "  bet %d: %s\n", j, bet.Format(moneyFormat))
+			}
+		case "help":
+			fmt.Fprintln(w, replHelp)
+		case "quit":
+			return
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package roulette
+
+// defaultAdaptiveWindow is how many recent spins adaptive betting looks back
+// over when the strategy doesn't give a window
+const defaultAdaptiveWindow = 37
+
+// spinWindow keeps count of the pockets hit over the last few spins
+type spinWindow struct {
+	size   int
+	recent []int // Oldest first
+	counts map[int]int
+}
+
+// newSpinWindow returns an empty window over the last size spins
+func newSpinWindow(size int) *spinWindow {
+	return &spinWindow{size: size, counts: make(map[int]int)}
+}
+
+// add records a winning number, dropping the oldest once the window is full
+func (w *spinWindow) add(n int) {
+	w.recent = append(w.recent, n)
+	w.counts[n]++
+	if len(w.recent) > w.size {
+		w.counts[w.recent[0]]--
+		w.recent = w.recent[1:]
+	}
+}
+
+// target returns the pocket adaptive number bets should move to: the most
+// frequent in the window for "hot", or the least frequent for "cold", with
+// ties going to the pocket that comes first in pockets. It reports false
+// until the window has seen a spin.
+func (w *spinWindow) target(mode string, pockets []int) (int, bool) {
+	if len(w.recent) == 0 {
+		return 0, false
+	}
+	best := pockets[0]
+	for _, pocket := range pockets[1:] {
+		if mode == "hot" && w.counts[pocket] > w.counts[best] {
+			best = pocket
+		}
+		if mode == "cold" && w.counts[pocket] < w.counts[best] {
+			best = pocket
+		}
+	}
+	return best, true
+}
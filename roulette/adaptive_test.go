@@ -0,0 +1,78 @@
+package roulette
+
+import (
+	"reflect"
+	"testing"
+)
+
+// adaptiveTargets replays spins and returns the pocket the number bet was
+// moved to on each, or -2 for a spin played without a target
+func adaptiveTargets(t *testing.T, text string, spins []int) []int {
+	t.Helper()
+	result, err := ReplayWithOptions(mustParse(t, text), spins, SimulationOptions{RecordSpins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := make([]int, len(result.SpinLog))
+	for i, record := range result.SpinLog {
+		targets[i] = -2
+		if record.Target != nil {
+			targets[i] = *record.Target
+		}
+	}
+	return targets
+}
+
+func TestSpinWindowTarget(t *testing.T) {
+	pockets := []int{1, 2, 3, 4}
+	w := newSpinWindow(3)
+	if _, ok := w.target("hot", pockets); ok {
+		t.Error("empty window gave a target")
+	}
+
+	w.add(2)
+	w.add(2)
+	w.add(3)
+	if got, _ := w.target("hot", pockets); got != 2 {
+		t.Errorf("hot target = %d, want 2", got)
+	}
+	if got, _ := w.target("cold", pockets); got != 1 {
+		t.Errorf("cold target = %d, want 1 (first unseen pocket)", got)
+	}
+
+	// The first 2 drops out, leaving 2, 3, 3
+	w.add(3)
+	if got, _ := w.target("hot", pockets); got != 3 {
+		t.Errorf("hot target after eviction = %d, want 3", got)
+	}
+
+	// 3, 1 and 4 are level, so the first of them wins
+	w.add(1)
+	w.add(4)
+	if got, _ := w.target("hot", pockets); got != 1 {
+		t.Errorf("hot target = %d, want 1 (ties go to the first pocket)", got)
+	}
+	if got, _ := w.target("cold", pockets); got != 2 {
+		t.Errorf("cold target = %d, want 2", got)
+	}
+}
+
+func TestHotTargetFollowsWindow(t *testing.T) {
+	got := adaptiveTargets(t, "bankroll: 1000\nadaptive: hot\nwindow: 3\nbet: number, 17, 1\n",
+		[]int{5, 5, 7, 7, 7, 9, 9, 9})
+	// Each spin bets on the hottest number of the three before it
+	want := []int{-2, 5, 5, 5, 7, 7, 7, 9}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %v, want %v", got, want)
+	}
+}
+
+func TestColdTargetFollowsWindow(t *testing.T) {
+	got := adaptiveTargets(t, "bankroll: 1000\nadaptive: cold\nwindow: 2\nbet: number, 17, 1\n",
+		[]int{1, 2, 3, 1, 4})
+	// The coldest pocket is the first on the wheel missing from the last two
+	want := []int{-2, 2, 3, 1, 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %v, want %v", got, want)
+	}
+}
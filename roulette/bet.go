@@ -0,0 +1,492 @@
+package roulette
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bet represents a single bet in roulette
+type Bet struct {
+	Type    string
+	Value   int
+	Values  []int // Numbers covered by multi-number bets such as splits
+	Amount  float64
+	Percent float64 // Stake as a percentage of the current bankroll, instead of Amount
+	Group   string  // Schedule group the bet belongs to, empty to play every spin
+	Section string  // Section bet this chip was placed for, if any
+	Phase   int     // 1-based index of the Phase the bet plays in, zero to play in every phase
+}
+
+// String describes the bet the way a player would call it, such as
+// "red $10.00", "split 17/20 $5.00" or "number 00 2% of bankroll"
+func (bet Bet) String() string {
+	return bet.Format(DefaultMoneyFormat)
+}
+
+// Format describes the bet like String, writing its stake in the money format f
+func (bet Bet) Format(f MoneyFormat) string {
+	var b strings.Builder
+	b.WriteString(bet.Type)
+	switch {
+	case len(bet.Values) > 0:
+		labels := make([]string, len(bet.Values))
+		for i, n := range bet.Values {
+			labels[i] = PocketLabel(n)
+		}
+		b.WriteString(" " + strings.Join(labels, "/"))
+	case bet.Type == "street":
+		fmt.Fprintf(&b, " %d-%d", bet.Value, bet.Value+2)
+	case bet.Type == "line":
+		fmt.Fprintf(&b, " %d-%d", bet.Value, bet.Value+5)
+	case bet.Type == "number" || bet.Type == "dozen" || bet.Type == "column":
+		b.WriteString(" " + PocketLabel(bet.Value))
+	}
+	if bet.Percent > 0 {
+		fmt.Fprintf(&b, " %v%% of bankroll", bet.Percent)
+	} else {
+		b.WriteString(" " + f.Format(ToCents(bet.Amount)))
+	}
+	if bet.Section != "" {
+		fmt.Fprintf(&b, " (%s)", bet.Section)
+	}
+	return b.String()
+}
+
+// sectionChip is one chip, or a stack of chips, of a section bet
+type sectionChip struct {
+	Type    string
+	Numbers []int
+	Chips   int
+}
+
+// sections are the French call bets on parts of the wheel, as the chips the
+// dealer places for each of them
+var sections = map[string][]sectionChip{
+	// Voisins du zéro covers the 17 pockets from 22 to 25 around the zero
+	"voisins": {
+		{"trio", []int{0, 2, 3}, 2},
+		{"split", []int{4, 7}, 1},
+		{"split", []int{12, 15}, 1},
+		{"split", []int{18, 21}, 1},
+		{"split", []int{19, 22}, 1},
+		{"split", []int{32, 35}, 1},
+		{"corner", []int{25, 26, 28, 29}, 2},
+	},
+	// Tiers du cylindre covers the 12 pockets from 27 to 33 opposite the zero
+	"tiers": {
+		{"split", []int{5, 8}, 1},
+		{"split", []int{10, 11}, 1},
+		{"split", []int{13, 16}, 1},
+		{"split", []int{23, 24}, 1},
+		{"split", []int{27, 30}, 1},
+		{"split", []int{33, 36}, 1},
+	},
+	// Orphelins covers the 8 pockets left over by the other two sections
+	"orphelins": {
+		{"number", []int{1}, 1},
+		{"split", []int{6, 9}, 1},
+		{"split", []int{14, 17}, 1},
+		{"split", []int{17, 20}, 1},
+		{"split", []int{31, 34}, 1},
+	},
+}
+
+// sectionBets expands a section bet into its chips, staking the amount or
+// percentage of chip on each one
+func sectionBets(name string, chip Bet) ([]Bet, error) {
+	name = strings.ToLower(name)
+	placements, ok := sections[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown section %s: must be voisins, tiers or orphelins", name)
+	}
+	if err := validateBet(Bet{Type: "number", Amount: chip.Amount, Percent: chip.Percent}); err != nil {
+		return nil, err
+	}
+	var bets []Bet
+	for _, placement := range placements {
+		bet := Bet{
+			Type:    placement.Type,
+			Amount:  chip.Amount * float64(placement.Chips),
+			Percent: chip.Percent * float64(placement.Chips),
+			Section: name,
+		}
+		if placement.Type == "number" {
+			bet.Value = placement.Numbers[0]
+		} else {
+			bet.Values = placement.Numbers
+		}
+		bets = append(bets, bet)
+	}
+	return bets, nil
+}
+
+// numbersBets expands a numbers bet into a straight-up bet on each number
+// listed in field, each staking the full amount or percentage of chip, so
+// "bet: numbers, 5 17 23, 10" risks $30 a spin and a hit returns $360 of it
+func numbersBets(field string, chip Bet) ([]Bet, error) {
+	var bets []Bet
+	var numbers []int
+	for _, f := range strings.Fields(field) {
+		n, err := parseBetNumber(f)
+		if err != nil {
+			return nil, err
+		}
+		if contains(numbers, n) {
+			return nil, fmt.Errorf("number %s is listed more than once", PocketLabel(n))
+		}
+		numbers = append(numbers, n)
+		bet := Bet{Type: "number", Value: n, Amount: chip.Amount, Percent: chip.Percent}
+		if err := validateBet(bet); err != nil {
+			return nil, err
+		}
+		bets = append(bets, bet)
+	}
+	if len(bets) == 0 {
+		return nil, fmt.Errorf("numbers bet needs at least one number")
+	}
+	return bets, nil
+}
+
+// twoDozensBets expands a two_dozens bet into a dozen bet on each of the two
+// dozens listed in field, each staking the full amount or percentage of chip,
+// so "bet: two_dozens, 1 2, 10" risks $20 a spin to cover 1 to 24
+func twoDozensBets(field string, chip Bet) ([]Bet, error) {
+	fields := strings.Fields(field)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("two_dozens bet needs two dozens, got %d", len(fields))
+	}
+	var bets []Bet
+	for _, f := range fields {
+		dozen, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dozen %q", f)
+		}
+		bet := Bet{Type: "dozen", Value: dozen, Amount: chip.Amount, Percent: chip.Percent}
+		if err := validateBet(bet); err != nil {
+			return nil, err
+		}
+		bets = append(bets, bet)
+	}
+	if bets[0].Value == bets[1].Value {
+		return nil, fmt.Errorf("two_dozens bet needs two different dozens, got %d twice", bets[0].Value)
+	}
+	return bets, nil
+}
+
+// spreadBets parses the chips of a number bet spread unevenly over several
+// numbers, written as number:amount pairs such as "5:10 17:20 0:5", into a
+// straight-up bet on each number
+func spreadBets(field string) ([]Bet, error) {
+	var bets []Bet
+	var numbers []int
+	for _, token := range strings.Fields(field) {
+		numberStr, amountStr, ok := strings.Cut(token, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid chip %q: must be number:amount", token)
+		}
+		n, err := parseBetNumber(numberStr)
+		if err != nil {
+			return nil, err
+		}
+		if contains(numbers, n) {
+			return nil, fmt.Errorf("number %s is listed more than once", PocketLabel(n))
+		}
+		numbers = append(numbers, n)
+		bet := Bet{Type: "number", Value: n}
+		if err := parseStake(&bet, amountStr); err != nil {
+			return nil, err
+		}
+		if err := validateBet(bet); err != nil {
+			return nil, err
+		}
+		bets = append(bets, bet)
+	}
+	if len(bets) == 0 {
+		return nil, fmt.Errorf("number bet needs at least one number:amount chip")
+	}
+	return bets, nil
+}
+
+// parseStake sets the stake of bet from an amount, or from a percentage of
+// the bankroll when it ends in %
+func parseStake(bet *Bet, amountStr string) error {
+	if percentStr, ok := strings.CutSuffix(amountStr, "%"); ok {
+		percent, err := parseAmount(strings.TrimSpace(percentStr))
+		if err != nil {
+			return fmt.Errorf("invalid bet percentage: %v", err)
+		}
+		bet.Percent = percent
+		return nil
+	}
+	amount, err := parseAmount(amountStr)
+	if err != nil {
+		return fmt.Errorf("invalid bet amount: %v", err)
+	}
+	bet.Amount = amount
+	return nil
+}
+
+// parseBet parses the "type, value, amount" part of a bet line. Most lines
+// hold a single bet, but a section bet expands into the chips that make it up,
+// a two_dozens bet into its two dozens, and a numbers bet or a number bet
+// given as number:amount chips into one straight-up per number.
+func parseBet(betStr string) ([]Bet, error) {
+	parts := strings.Split(betStr, ",")
+	if len(parts) == 2 && strings.TrimSpace(parts[0]) == "number" && strings.Contains(parts[1], ":") {
+		return spreadBets(parts[1])
+	}
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid bet format")
+	}
+	betType := strings.TrimSpace(parts[0])
+	bet := Bet{Type: betType}
+	if err := parseStake(&bet, strings.TrimSpace(parts[2])); err != nil {
+		return nil, err
+	}
+
+	if betType == "section" {
+		return sectionBets(strings.TrimSpace(parts[1]), bet)
+	}
+	if betType == "numbers" {
+		return numbersBets(parts[1], bet)
+	}
+	if betType == "two_dozens" {
+		return twoDozensBets(parts[1], bet)
+	}
+	if isMultiNumberBet(betType) {
+		for _, field := range strings.Fields(parts[1]) {
+			n, err := parseBetNumber(field)
+			if err != nil {
+				return nil, err
+			}
+			bet.Values = append(bet.Values, n)
+		}
+		// A lone number is an anchor standing in for the whole block
+		if len(bet.Values) == 1 && betType != "trio" {
+			numbers, err := BetNumbers(betType, bet.Values[0])
+			if err != nil {
+				return nil, err
+			}
+			bet.Values = numbers
+		}
+	} else {
+		value, err := parseBetNumber(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		bet.Value = value
+	}
+	if err := validateBet(bet); err != nil {
+		return nil, err
+	}
+	return []Bet{bet}, nil
+}
+
+// validateBet checks that a bet's amount is positive and its value is legal
+// for its type
+func validateBet(bet Bet) error {
+	if bet.Percent != 0 {
+		if bet.Amount != 0 {
+			return fmt.Errorf("bet can't have both an amount and a percentage")
+		}
+		if bet.Percent <= 0 || bet.Percent > 100 {
+			return fmt.Errorf("bet percentage must be above 0%% and at most 100%%, got %v%%", bet.Percent)
+		}
+	} else if bet.Amount <= 0 {
+		return fmt.Errorf("bet amount must be positive, got %v", bet.Amount)
+	}
+	if _, ok := payoutMultiples[bet.Type]; !ok {
+		return fmt.Errorf("unknown bet type: %s", bet.Type)
+	}
+	switch bet.Type {
+	case "number":
+		if !isPocket(bet.Value) {
+			return fmt.Errorf("invalid number %d: must be 0-36 or 00", bet.Value)
+		}
+	case "dozen":
+		if bet.Value < 1 || bet.Value > 3 {
+			return fmt.Errorf("invalid dozen %d: must be 1, 2 or 3", bet.Value)
+		}
+	case "column":
+		if bet.Value < 1 || bet.Value > 3 {
+			return fmt.Errorf("invalid column %d: must be 1, 2 or 3", bet.Value)
+		}
+	case "split":
+		if len(bet.Values) != 2 {
+			return fmt.Errorf("split bet needs exactly 2 numbers, got %d", len(bet.Values))
+		}
+		if !adjacent(bet.Values[0], bet.Values[1]) {
+			return fmt.Errorf("%s and %s are not adjacent", PocketLabel(bet.Values[0]), PocketLabel(bet.Values[1]))
+		}
+	case "corner":
+		if len(bet.Values) != 4 {
+			return fmt.Errorf("corner bet needs exactly 4 numbers, got %d", len(bet.Values))
+		}
+		if !isCorner(bet.Values) {
+			return fmt.Errorf("%v do not form a corner", bet.Values)
+		}
+	case "trio":
+		if !isTrio(bet.Values) {
+			return fmt.Errorf("%v is not a trio: must be 0 1 2, 0 2 3 or 00 2 3", bet.Values)
+		}
+	case "street":
+		if !isRowStart(bet.Value) {
+			return fmt.Errorf("invalid street start %d: must be one of 1, 4, 7, ..., 34", bet.Value)
+		}
+	case "line":
+		// The second row of the line must exist too, so 34 can't start one
+		if !isRowStart(bet.Value) || bet.Value > 31 {
+			return fmt.Errorf("invalid line start %d: must be one of 1, 4, 7, ..., 31", bet.Value)
+		}
+	}
+	return nil
+}
+
+// isPocket reports whether n is a pocket on some wheel
+func isPocket(n int) bool {
+	return (n >= 0 && n <= 36) || n == DoubleZero
+}
+
+// isMultiNumberBet reports whether a bet type lists its numbers in Values
+func isMultiNumberBet(betType string) bool {
+	switch betType {
+	case "split", "corner", "trio":
+		return true
+	}
+	return false
+}
+
+// parseAmount parses a money amount or other decimal setting. Digits may be
+// grouped with underscores and exponents are allowed, as in 1_000 or 1e6,
+// but NaN and infinities are not.
+func parseAmount(s string) (float64, error) {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%q is not a finite number", s)
+	}
+	return value, nil
+}
+
+// parseBetNumber parses one number from the value part of a bet line. The
+// double zero is written "00", which would otherwise read as plain 0.
+func parseBetNumber(field string) (int, error) {
+	if field == "00" {
+		return DoubleZero, nil
+	}
+	n, err := strconv.Atoi(field)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid bet value %q", field)
+	}
+	return n, nil
+}
+
+// BetNumbers returns the pockets an inside bet covers given a single anchor
+// number, so a block doesn't have to be typed out in full. A number bet
+// covers just the anchor, a split the anchor and the number to its right,
+// and a corner the 2x2 block with the anchor at its top left. A street is
+// the row holding the anchor, and a line that row and the one after it;
+// bet lines still give those by their row start, so a mistyped start is
+// reported rather than moved to another row.
+func BetNumbers(betType string, anchor int) ([]int, error) {
+	if anchor < 1 || anchor > 36 {
+		if betType == "number" && isPocket(anchor) {
+			return []int{anchor}, nil
+		}
+		return nil, fmt.Errorf("%s anchor must be between 1 and 36, got %d", betType, anchor)
+	}
+	rowStart := (anchor-1)/3*3 + 1
+	switch betType {
+	case "number":
+		return []int{anchor}, nil
+	case "split":
+		if anchor%3 == 0 {
+			return nil, fmt.Errorf("split anchor %d is in the last column", anchor)
+		}
+		return []int{anchor, anchor + 1}, nil
+	case "corner":
+		if anchor%3 == 0 || anchor > 32 {
+			return nil, fmt.Errorf("corner anchor %d must not be in the last column or row", anchor)
+		}
+		return []int{anchor, anchor + 1, anchor + 3, anchor + 4}, nil
+	case "street":
+		return []int{rowStart, rowStart + 1, rowStart + 2}, nil
+	case "line":
+		if rowStart > 31 {
+			return nil, fmt.Errorf("line anchor %d is in the last row", anchor)
+		}
+		return []int{rowStart, rowStart + 1, rowStart + 2, rowStart + 3, rowStart + 4, rowStart + 5}, nil
+	}
+	return nil, fmt.Errorf("%s bets can't be given by an anchor", betType)
+}
+
+// isRowStart reports whether n is the first number of a row on the table layout
+func isRowStart(n int) bool {
+	return n >= 1 && n <= 34 && (n-1)%3 == 0
+}
+
+// isTrio reports whether three numbers form a trio, a street that includes a
+// green pocket
+func isTrio(numbers []int) bool {
+	if len(numbers) != 3 {
+		return false
+	}
+	sorted := append([]int(nil), numbers...)
+	sort.Ints(sorted)
+	for _, trio := range [][]int{{0, 1, 2}, {0, 2, 3}, {DoubleZero, 2, 3}} {
+		if sorted[0] == trio[0] && sorted[1] == trio[1] && sorted[2] == trio[2] {
+			return true
+		}
+	}
+	return false
+}
+
+// isCorner reports whether four numbers form a 2x2 block on the table layout
+func isCorner(numbers []int) bool {
+	sorted := append([]int(nil), numbers...)
+	sort.Ints(sorted)
+	first := sorted[0]
+	// The lowest number of a corner can't sit in the third column or the
+	// last row
+	if first < 1 || first > 32 || first%3 == 0 {
+		return false
+	}
+	return sorted[1] == first+1 && sorted[2] == first+3 && sorted[3] == first+4
+}
+
+// adjacent reports whether a and b share an edge on the standard 3-column
+// table layout, where each row holds three consecutive numbers and the green
+// pockets sit above the first row. Green splits are those of either wheel:
+// 0 with 1, 2 or 3 and, on American tables, 00 with 2, 3 or 0; checkBetWheel
+// rules out the ones the strategy's wheel doesn't have.
+func adjacent(a, b int) bool {
+	if a > b {
+		a, b = b, a
+	}
+	switch a {
+	case DoubleZero:
+		return b == 0 || b == 2 || b == 3
+	case 0:
+		return b >= 1 && b <= 3
+	}
+	if b > 36 {
+		return false
+	}
+	sameRow := (a-1)/3 == (b-1)/3
+	return (b-a == 1 && sameRow) || b-a == 3
+}
+
+// contains checks if a slice contains a specific value
+func contains(slice []int, val int) bool {
+	for _, item := range slice {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,403 @@
+package roulette
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseBetError parses a strategy holding the given bet lines and returns
+// the error, if any
+func parseBetError(lines string) error {
+	_, err := ParseStrategy("bankroll: 1000\n" + lines)
+	return err
+}
+
+func TestSplitBets(t *testing.T) {
+	for _, pair := range []string{"5 6", "5 8", "0 1", "0 2"} {
+		if err := parseBetError("bet: split, " + pair + ", 10\n"); err != nil {
+			t.Errorf("split %s: %v", pair, err)
+		}
+	}
+	err := parseBetError("bet: split, 5 12, 10\n")
+	if err == nil || !strings.Contains(err.Error(), "5 and 12 are not adjacent") {
+		t.Errorf("split 5 12: got error %v, want 5 and 12 are not adjacent", err)
+	}
+	if err := parseBetError("bet: split, 3 4, 10\n"); err == nil {
+		t.Error("split 3 4 across the end of a row was accepted")
+	}
+
+	// A winning split pays 17 to 1
+	checkCovers(t, "split, 5 8", []int{5, 8}, 17)
+}
+
+func TestGreenSplits(t *testing.T) {
+	tests := []struct {
+		wheel, pair string
+		ok          bool
+	}{
+		{"american", "00 3", true},
+		{"american", "00 2", true},
+		{"american", "0 00", true},
+		{"american", "0 1", true},
+		{"american", "0 2", true},
+		{"american", "0 3", false},
+		{"european", "0 3", true},
+		{"european", "00 3", false},
+		{"american", "00 1", false},
+		{"american", "0 4", false},
+	}
+	for _, tt := range tests {
+		err := parseBetError("wheel: " + tt.wheel + "\nbet: split, " + tt.pair + ", 10\n")
+		if (err == nil) != tt.ok {
+			t.Errorf("split %s on the %s wheel: got error %v, want ok %v", tt.pair, tt.wheel, err, tt.ok)
+		}
+	}
+}
+
+func TestCornerBets(t *testing.T) {
+	for _, corner := range []string{"1 2 4 5", "23 24 26 27", "32 33 35 36", "5 4 2 1"} {
+		if err := parseBetError("bet: corner, " + corner + ", 10\n"); err != nil {
+			t.Errorf("corner %s: %v", corner, err)
+		}
+	}
+	for _, corner := range []string{"2 3 4 5", "1 2 3 4", "3 4 6 7", "33 34 36 37", "1 2 4"} {
+		if err := parseBetError("bet: corner, " + corner + ", 10\n"); err == nil {
+			t.Errorf("corner %s was accepted", corner)
+		}
+	}
+
+	// A winning corner pays 8 to 1
+	checkCovers(t, "corner, 23 24 26 27", []int{23, 24, 26, 27}, 8)
+}
+
+func TestStreetBets(t *testing.T) {
+	for _, start := range []string{"1", "7", "34"} {
+		if err := parseBetError("bet: street, " + start + ", 10\n"); err != nil {
+			t.Errorf("street %s: %v", start, err)
+		}
+	}
+	err := parseBetError("bet: street, 2, 10\n")
+	if err == nil || !strings.Contains(err.Error(), "invalid street start 2") {
+		t.Errorf("street 2: got error %v, want invalid street start 2", err)
+	}
+
+	// A winning street pays 11 to 1
+	checkCovers(t, "street, 1", []int{1, 2, 3}, 11)
+	checkCovers(t, "street, 7", []int{7, 8, 9}, 11)
+	checkCovers(t, "street, 34", []int{34, 35, 36}, 11)
+}
+
+func TestNumberBetRange(t *testing.T) {
+	for _, number := range []string{"0", "36", "00"} {
+		if err := parseBetError("bet: number, " + number + ", 10\n"); err != nil {
+			t.Errorf("number %s: %v", number, err)
+		}
+	}
+	for _, number := range []string{"37", "99", "-3"} {
+		err := parseBetError("bet: number, " + number + ", 10\n")
+		if err == nil || !strings.Contains(err.Error(), "on line 2: bet: number, "+number+", 10") {
+			t.Errorf("number %s: got error %v, want one naming line 2", number, err)
+		}
+	}
+	if err := parseBetError("bet: split, 36 37, 10\n"); err == nil {
+		t.Error("split 36 37 was accepted")
+	}
+}
+
+func TestNonPositiveAmounts(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"bankroll: 100\nbet: even, 0, -5\n", "bet amount must be positive, got -5 on line 2"},
+		{"bankroll: 100\nbet: even, 0, 0\n", "bet amount must be positive, got 0 on line 2"},
+		{"bankroll: -100\nbet: even, 0, 5\n", "bankroll must be positive"},
+	}
+	for _, tt := range tests {
+		_, err := ParseStrategy(tt.text)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: got error %v, want %q", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestLineBets(t *testing.T) {
+	for _, start := range []string{"1", "16", "31"} {
+		if err := parseBetError("bet: line, " + start + ", 10\n"); err != nil {
+			t.Errorf("line %s: %v", start, err)
+		}
+	}
+	for _, start := range []int{2, 34} {
+		if err := validateBet(Bet{Type: "line", Value: start, Amount: 10}); err == nil {
+			t.Errorf("line %d was accepted", start)
+		}
+	}
+	if err := parseBetError("bet: line, 34, 10\n"); err == nil {
+		t.Error("a line starting in the last row was accepted")
+	}
+
+	// A winning line pays 5 to 1
+	checkCovers(t, "line, 31", []int{31, 32, 33, 34, 35, 36}, 5)
+	checkCovers(t, "line, 16", []int{16, 17, 18, 19, 20, 21}, 5)
+}
+
+func TestBasketBets(t *testing.T) {
+	// A winning basket pays 6 to 1
+	checkCovers(t, "basket, 0", []int{0, DoubleZero, 1, 2, 3}, 6)
+	if err := parseBetError("bet: basket, 0, 10\n"); err != nil {
+		t.Errorf("basket on the american wheel: %v", err)
+	}
+	if err := parseBetError("wheel: european\nbet: basket, 0, 10\n"); err == nil {
+		t.Error("basket on the european wheel was accepted")
+	}
+}
+
+func TestPercentStakes(t *testing.T) {
+	_, stakes := replayStakes(t, "bankroll: 1000\nbet: even, 0, 10%\n", []int{1, 1, 1, 1, 1, 2})
+	want := []float64{100, 90, 81, 72.9, 65.61, 59.05}
+	if !reflect.DeepEqual(stakes, want) {
+		t.Errorf("stakes = %v, want %v", stakes, want)
+	}
+
+	if err := validateBet(Bet{Type: "even", Amount: 10, Percent: 5}); err == nil {
+		t.Error("a bet with both an amount and a percentage was accepted")
+	}
+	for _, percent := range []string{"0%", "-5%", "150%"} {
+		if err := parseBetError("bet: even, 0, " + percent + "\n"); err == nil {
+			t.Errorf("percentage %s was accepted", percent)
+		}
+	}
+	if err := parseBetError("bet: even, 0, 100%\n"); err != nil {
+		t.Errorf("percentage 100%%: %v", err)
+	}
+}
+
+func TestSectionBets(t *testing.T) {
+	tests := []struct {
+		section string
+		pockets []int
+		chips   float64
+	}{
+		{"voisins", []int{22, 18, 29, 7, 28, 12, 35, 3, 26, 0, 32, 15, 19, 4, 21, 2, 25}, 9},
+		{"tiers", []int{27, 13, 36, 11, 30, 8, 23, 10, 5, 24, 16, 33}, 6},
+		{"orphelins", []int{17, 34, 6, 1, 20, 14, 31, 9}, 5},
+	}
+	for _, tt := range tests {
+		strategy, err := ParseStrategy("bankroll: 1000\nwheel: european\nbet: section, " + tt.section + ", 1\n")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.section, err)
+		}
+		stake := 0.0
+		for _, bet := range strategy.Bets {
+			stake += bet.Amount
+		}
+		if stake != tt.chips {
+			t.Errorf("%s stakes %v chips, want %v", tt.section, stake, tt.chips)
+		}
+		for n := 0; n <= 36; n++ {
+			covered := false
+			for _, bet := range strategy.Bets {
+				covered = covered || betWins(bet, n)
+			}
+			if want := contains(tt.pockets, n); covered != want {
+				t.Errorf("%s covers %d = %v, want %v", tt.section, n, covered, want)
+			}
+		}
+	}
+
+	if err := parseBetError("bet: section, voisins, 1\n"); err == nil {
+		t.Error("a section bet on the american wheel was accepted")
+	}
+	if err := parseBetError("wheel: european\nbet: section, neighbours, 1\n"); err == nil {
+		t.Error("an unknown section was accepted")
+	}
+}
+
+func TestBetNumbers(t *testing.T) {
+	tests := []struct {
+		bet    string
+		anchor int
+		want   []int
+	}{
+		{"number", 17, []int{17}},
+		{"number", DoubleZero, []int{DoubleZero}},
+		{"split", 5, []int{5, 6}},
+		{"corner", 5, []int{5, 6, 8, 9}},
+		{"corner", 31, []int{31, 32, 34, 35}},
+		{"street", 8, []int{7, 8, 9}},
+		{"street", 34, []int{34, 35, 36}},
+		{"line", 2, []int{1, 2, 3, 4, 5, 6}},
+		{"line", 30, []int{28, 29, 30, 31, 32, 33}},
+	}
+	for _, tt := range tests {
+		got, err := BetNumbers(tt.bet, tt.anchor)
+		if err != nil {
+			t.Errorf("%s %d: %v", tt.bet, tt.anchor, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %d = %v, want %v", tt.bet, tt.anchor, got, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		bet    string
+		anchor int
+	}{{"split", 6}, {"corner", 3}, {"corner", 34}, {"line", 35}, {"street", 0}, {"dozen", 5}} {
+		if got, err := BetNumbers(tt.bet, tt.anchor); err == nil {
+			t.Errorf("%s %d resolved to %v", tt.bet, tt.anchor, got)
+		}
+	}
+
+	strategy, err := ParseStrategy("bankroll: 100\nbet: corner, 5, 10\nbet: street, 7, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strategy.Bets[0].Values; !reflect.DeepEqual(got, []int{5, 6, 8, 9}) {
+		t.Errorf("corner 5 covers %v, want 5 6 8 9", got)
+	}
+	if got := strategy.Bets[1].Value; got != 7 {
+		t.Errorf("street 7 starts at %d, want 7", got)
+	}
+	// Streets and lines in a bet line are still given by their row start
+	for _, line := range []string{"bet: street, 2, 10\n", "bet: line, 5, 10\n"} {
+		if err := parseBetError(line); err == nil {
+			t.Errorf("%q was accepted", line)
+		}
+	}
+}
+
+func TestGreenNumberBets(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 100\nbet: number, 0, 10\nbet: number, 00, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero, doubleZero := strategy.Bets[0], strategy.Bets[1]
+	if zero.Value != 0 || doubleZero.Value != DoubleZero {
+		t.Fatalf("parsed %d and %d, want 0 and %d", zero.Value, doubleZero.Value, DoubleZero)
+	}
+	for _, n := range NewWheel(American).Numbers {
+		if betWins(zero, n) != (n == 0) {
+			t.Errorf("a bet on 0 settled wrongly on %s", PocketLabel(n))
+		}
+		if betWins(doubleZero, n) != (n == DoubleZero) {
+			t.Errorf("a bet on 00 settled wrongly on %s", PocketLabel(n))
+		}
+	}
+}
+
+func TestNumbersBets(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\nbet: numbers, 5 17 00 34, 10\n")
+	if len(strategy.Bets) != 4 {
+		t.Fatalf("numbers bet placed %d bets, want 4", len(strategy.Bets))
+	}
+	for i, want := range []int{5, 17, DoubleZero, 34} {
+		if bet := strategy.Bets[i]; bet.Type != "number" || bet.Value != want || bet.Amount != 10 {
+			t.Errorf("bet %d = %+v, want a straight-up $10 on %s", i, bet, PocketLabel(want))
+		}
+	}
+
+	// $40 a spin: a hit returns $360 on the winning number, a miss loses it all
+	hit, err := SimulateWithSpins(strategy, []int{17})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hit.FinalBankroll != 420 {
+		t.Errorf("after a hit the bankroll is %v, want 420", hit.FinalBankroll)
+	}
+	miss, err := SimulateWithSpins(strategy, []int{18})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if miss.FinalBankroll != 60 {
+		t.Errorf("after a miss the bankroll is %v, want 60", miss.FinalBankroll)
+	}
+
+	for _, field := range []string{"5 17 5", "5 37", "5 seven"} {
+		if err := parseBetError("bet: numbers, " + field + ", 10\n"); err == nil {
+			t.Errorf("numbers %s was accepted", field)
+		}
+	}
+}
+
+func TestPerNumberStakes(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\nbet: number, 5:10 17:20 0:5 00:2.5\n")
+	want := []Bet{
+		{Type: "number", Value: 5, Amount: 10},
+		{Type: "number", Value: 17, Amount: 20},
+		{Type: "number", Value: 0, Amount: 5},
+		{Type: "number", Value: DoubleZero, Amount: 2.5},
+	}
+	if !reflect.DeepEqual(strategy.Bets, want) {
+		t.Errorf("bets = %+v, want %+v", strategy.Bets, want)
+	}
+
+	for _, field := range []string{"5:10 17", "5:ten", "37:10", "5:-1", "5:10 5:20", ":10", "5:10 17:20, 5"} {
+		if err := parseBetError("bet: number, " + field + "\n"); err == nil {
+			t.Errorf("number %s was accepted", field)
+		}
+	}
+}
+
+func TestBetString(t *testing.T) {
+	for _, tt := range []struct {
+		bet  Bet
+		want string
+	}{
+		{Bet{Type: "even", Amount: 10}, "even $10.00"},
+		{Bet{Type: "number", Value: DoubleZero, Amount: 2.5}, "number 00 $2.50"},
+		{Bet{Type: "split", Values: []int{17, 20}, Amount: 5}, "split 17/20 $5.00"},
+		{Bet{Type: "corner", Values: []int{1, 2, 4, 5}, Amount: 1234}, "corner 1/2/4/5 $1,234.00"},
+		{Bet{Type: "street", Value: 4, Amount: 5}, "street 4-6 $5.00"},
+		{Bet{Type: "line", Value: 7, Amount: 5}, "line 7-12 $5.00"},
+		{Bet{Type: "dozen", Value: 2, Percent: 5}, "dozen 2 5% of bankroll"},
+		{Bet{Type: "basket", Amount: 5}, "basket $5.00"},
+		{Bet{Type: "split", Values: []int{5, 8}, Amount: 1, Section: "tiers"}, "split 5/8 $1.00 (tiers)"},
+	} {
+		if got := tt.bet.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+	if got := (Bet{Type: "red", Amount: 10}).Format(MoneyFormat{Symbol: "units", Places: 0}); got != "red 10 units" {
+		t.Errorf("Format() = %q, want %q", got, "red 10 units")
+	}
+}
+
+func TestTwoDozens(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 1000\nbet: two_dozens, 1 3, 10\nbet: two_dozens, 2 3, 5%\n")
+	want := []Bet{
+		{Type: "dozen", Value: 1, Amount: 10},
+		{Type: "dozen", Value: 3, Amount: 10},
+		{Type: "dozen", Value: 2, Percent: 5},
+		{Type: "dozen", Value: 3, Percent: 5},
+	}
+	if !reflect.DeepEqual(strategy.Bets, want) {
+		t.Errorf("bets = %+v, want %+v", strategy.Bets, want)
+	}
+
+	// 1 to 12 and 25 to 36 win 20 on a stake of 20, and everything else
+	// loses both stakes
+	for _, tt := range []struct {
+		spin int
+		net  float64
+	}{{5, 10}, {12, 10}, {25, 10}, {36, 10}, {13, -20}, {24, -20}, {0, -20}, {DoubleZero, -20}} {
+		result := replay(t, "bankroll: 100\nbet: two_dozens, 1 3, 10\n", tt.spin)
+		if got := result.FinalBankroll - 100; got != tt.net {
+			t.Errorf("two dozens 1 and 3 on %s: net %v, want %v", PocketLabel(tt.spin), got, tt.net)
+		}
+	}
+
+	for _, line := range []string{
+		"bet: two_dozens, 1, 10",
+		"bet: two_dozens, 1 2 3, 10",
+		"bet: two_dozens, 0 2, 10",
+		"bet: two_dozens, 1 4, 10",
+		"bet: two_dozens, 2 2, 10",
+		"bet: two_dozens, one two, 10",
+		"bet: two_dozens, 1 2, 0",
+	} {
+		if err := parseBetError(line + "\n"); err == nil {
+			t.Errorf("%q was accepted", line)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package roulette
+
+import (
+	"math"
+)
+
+// biasThreshold is how many standard deviations a pocket's count may stray
+// from its expected count before BiasTest flags it. Three keeps the chance
+// of flagging any of 38 fair pockets by luck to about one in ten.
+const biasThreshold = 3.0
+
+// PocketDeviation compares how often a pocket came up with how often a fair
+// wheel would produce it. Residual is the difference in standard deviations.
+type PocketDeviation struct {
+	Pocket   int     `json:"pocket"`
+	Observed int     `json:"observed"`
+	Expected float64 `json:"expected"`
+	Residual float64 `json:"residual"`
+}
+
+// BiasReport is the result of a chi-square goodness-of-fit test of a spin
+// sequence against a fair wheel
+type BiasReport struct {
+	Spins            int               `json:"spins"`
+	ChiSquare        float64           `json:"chi_square"`
+	DegreesOfFreedom int               `json:"degrees_of_freedom"`
+	CriticalValue    float64           `json:"critical_value"`
+	Biased           bool              `json:"biased"`
+	MostOver         PocketDeviation   `json:"most_over"`
+	MostUnder        PocketDeviation   `json:"most_under"`
+	Flagged          []PocketDeviation `json:"flagged,omitempty"`
+}
+
+// BiasTest checks whether spins look like they came from a fair wheel of the
+// given type. The sequence is judged biased when its chi-square statistic
+// exceeds the 5% critical value, and individual pockets are flagged when
+// their counts stray more than biasThreshold standard deviations.
+func BiasTest(spins []int, wheel WheelType) BiasReport {
+	pockets := NewWheel(wheel).Numbers
+	report := BiasReport{Spins: len(spins), DegreesOfFreedom: len(pockets) - 1}
+	report.CriticalValue = chiSquareCritical(report.DegreesOfFreedom)
+	if len(spins) == 0 {
+		return report
+	}
+
+	counts := make(map[int]int)
+	for _, n := range spins {
+		counts[n]++
+	}
+	expected := float64(len(spins)) / float64(len(pockets))
+	for i, pocket := range pockets {
+		deviation := PocketDeviation{
+			Pocket:   pocket,
+			Observed: counts[pocket],
+			Expected: expected,
+			Residual: (float64(counts[pocket]) - expected) / math.Sqrt(expected),
+		}
+		report.ChiSquare += deviation.Residual * deviation.Residual
+		if i == 0 || deviation.Residual > report.MostOver.Residual {
+			report.MostOver = deviation
+		}
+		if i == 0 || deviation.Residual < report.MostUnder.Residual {
+			report.MostUnder = deviation
+		}
+		if math.Abs(deviation.Residual) > biasThreshold {
+			report.Flagged = append(report.Flagged, deviation)
+		}
+	}
+	report.Biased = report.ChiSquare > report.CriticalValue
+	return report
+}
+
+// chiSquareCritical approximates the 5% critical value of the chi-square
+// distribution using the Wilson-Hilferty transformation, which is accurate
+// to well under 1% at the degrees of freedom a roulette wheel has
+func chiSquareCritical(df int) float64 {
+	const z = 1.6449 // 95th percentile of the standard normal
+	k := float64(df)
+	v := 2 / (9 * k)
+	return k * math.Pow(1-v+z*math.Sqrt(v), 3)
+}
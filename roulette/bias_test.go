@@ -0,0 +1,50 @@
+package roulette
+
+import (
+	"testing"
+)
+
+func TestBiasTestFlagsBiasedWheel(t *testing.T) {
+	wheel := NewSeededWheel(12)
+	spins := make([]int, 20000)
+	for i := range spins {
+		spins[i] = wheel.Spin()
+		if i%20 == 0 {
+			spins[i] = 17
+		}
+	}
+	report := BiasTest(spins, American)
+	if !report.Biased {
+		t.Errorf("chi-square %.1f below the critical %.1f for a wheel favouring 17", report.ChiSquare, report.CriticalValue)
+	}
+	if report.MostOver.Pocket != 17 {
+		t.Errorf("most over-represented pocket is %s, want 17", PocketLabel(report.MostOver.Pocket))
+	}
+	if len(report.Flagged) == 0 || report.Flagged[0].Pocket != 17 {
+		t.Errorf("flagged %+v, want 17 among them", report.Flagged)
+	}
+}
+
+func TestBiasTestPassesFairWheel(t *testing.T) {
+	wheel := newSeededWheel(European, 12)
+	spins := make([]int, 20000)
+	for i := range spins {
+		spins[i] = wheel.Spin()
+	}
+	report := BiasTest(spins, European)
+	if report.Biased || len(report.Flagged) != 0 {
+		t.Errorf("fair wheel judged biased: chi-square %.1f, critical %.1f, flagged %+v",
+			report.ChiSquare, report.CriticalValue, report.Flagged)
+	}
+	if report.DegreesOfFreedom != 36 || report.Spins != 20000 {
+		t.Errorf("%d degrees of freedom over %d spins, want 36 over 20000", report.DegreesOfFreedom, report.Spins)
+	}
+
+	var even []int
+	for i := 0; i < 10; i++ {
+		even = append(even, NewWheel(American).Numbers...)
+	}
+	if report := BiasTest(even, American); report.ChiSquare != 0 || report.Biased {
+		t.Errorf("a perfectly even sequence has chi-square %v", report.ChiSquare)
+	}
+}
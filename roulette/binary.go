@@ -0,0 +1,184 @@
+package roulette
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A binary spin log is a header followed by one fixed-width record per spin,
+// every field little-endian and money in whole cents. The header is 10 bytes:
+//
+//	magic    [4]byte  "RSPL"
+//	version  uint16   1
+//	bets     uint16   bets in the strategy, which fixes the record width
+//	wheels   uint16   wheels spun each round
+//
+// and each record is 22 + wheels + 8*bets + (bets+7)/8 bytes:
+//
+//	spin      uint32         1-based index of the spin
+//	flags     uint8          bit 0 set for a watched spin, bit 1 when target is set
+//	target    int8           pocket adaptive number bets were moved to
+//	numbers   [wheels]int8   winning number of each wheel, 00 as -1
+//	net       int64          net change in the bankroll
+//	bankroll  int64          bankroll after the spin was settled
+//	stakes    [bets]int64    stake placed on each bet
+//	skipped   [(bets+7)/8]byte  bit j%8 of byte j/8 set when bet j was skipped
+//
+// so a million spins of a single red bet take about 32MB, a fraction of the
+// same log as JSON.
+
+// spinLogMagic opens every binary spin log
+const spinLogMagic = "RSPL"
+
+// spinLogVersion is the version of the layout above
+const spinLogVersion = 1
+
+// binarySpinWriter encodes spin records in the binary spin log layout
+type binarySpinWriter struct {
+	w      *bufio.Writer
+	bets   int
+	wheels int
+	buf    []byte
+}
+
+// newBinarySpinWriter writes the header of a binary spin log for records of
+// the given number of bets and wheels
+func newBinarySpinWriter(w io.Writer, bets, wheels int) (*binarySpinWriter, error) {
+	if bets > 1<<16-1 || wheels > 1<<16-1 {
+		return nil, fmt.Errorf("too many bets or wheels for a binary spin log")
+	}
+	bw := &binarySpinWriter{
+		w:      bufio.NewWriter(w),
+		bets:   bets,
+		wheels: wheels,
+		buf:    make([]byte, spinRecordSize(bets, wheels)),
+	}
+	header := make([]byte, 10)
+	copy(header, spinLogMagic)
+	binary.LittleEndian.PutUint16(header[4:], spinLogVersion)
+	binary.LittleEndian.PutUint16(header[6:], uint16(bets))
+	binary.LittleEndian.PutUint16(header[8:], uint16(wheels))
+	_, err := bw.w.Write(header)
+	return bw, err
+}
+
+// spinRecordSize returns the width of a record with the given number of bets
+// and wheels
+func spinRecordSize(bets, wheels int) int {
+	return 22 + wheels + 8*bets + (bets+7)/8
+}
+
+// write encodes one spin record
+func (bw *binarySpinWriter) write(record SpinRecord) error {
+	buf := bw.buf
+	for i := range buf {
+		buf[i] = 0
+	}
+	binary.LittleEndian.PutUint32(buf[0:], uint32(record.Spin))
+	if record.Spectated {
+		buf[4] |= 1
+	}
+	if record.Target != nil {
+		buf[4] |= 2
+		buf[5] = byte(int8(*record.Target))
+	}
+	at := 6
+	numbers := record.WinningNumbers
+	if len(numbers) == 0 {
+		numbers = []int{record.WinningNumber}
+	}
+	if len(numbers) != bw.wheels || len(record.Stakes) != bw.bets {
+		return fmt.Errorf("spin %d doesn't fit a binary spin log of %d bets on %d wheels", record.Spin, bw.bets, bw.wheels)
+	}
+	for _, n := range numbers {
+		buf[at] = byte(int8(n))
+		at++
+	}
+	binary.LittleEndian.PutUint64(buf[at:], uint64(ToCents(record.NetChange)))
+	binary.LittleEndian.PutUint64(buf[at+8:], uint64(ToCents(record.Bankroll)))
+	at += 16
+	for _, stake := range record.Stakes {
+		binary.LittleEndian.PutUint64(buf[at:], uint64(ToCents(stake)))
+		at += 8
+	}
+	for _, j := range record.Skipped {
+		buf[at+j/8] |= 1 << (j % 8)
+	}
+	_, err := bw.w.Write(buf)
+	return err
+}
+
+// flush writes out any buffered records
+func (bw *binarySpinWriter) flush() error {
+	return bw.w.Flush()
+}
+
+// ReadSpinLogBinary reads back a spin log streamed with a SpinFormat of
+// "binary". Every field of the records comes back as it was written, with
+// WinningNumbers only filled in for strategies playing more than one wheel.
+func ReadSpinLogBinary(r io.Reader) ([]SpinRecord, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("reading binary spin log header: %w", err)
+	}
+	if string(header[:4]) != spinLogMagic {
+		return nil, fmt.Errorf("not a binary spin log")
+	}
+	if version := binary.LittleEndian.Uint16(header[4:]); version != spinLogVersion {
+		return nil, fmt.Errorf("unsupported binary spin log version %d", version)
+	}
+	bets := int(binary.LittleEndian.Uint16(header[6:]))
+	wheels := int(binary.LittleEndian.Uint16(header[8:]))
+	if wheels < 1 {
+		return nil, fmt.Errorf("binary spin log has no wheels")
+	}
+
+	var records []SpinRecord
+	buf := make([]byte, spinRecordSize(bets, wheels))
+	for {
+		if _, err := io.ReadFull(br, buf); err != nil {
+			if err == io.EOF {
+				return records, nil
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, fmt.Errorf("binary spin log ends partway through record %d", len(records)+1)
+			}
+			return records, err
+		}
+		record := SpinRecord{
+			Spin:      int(binary.LittleEndian.Uint32(buf[0:])),
+			Spectated: buf[4]&1 != 0,
+			Stakes:    make([]float64, bets),
+		}
+		if buf[4]&2 != 0 {
+			target := int(int8(buf[5]))
+			record.Target = &target
+		}
+		at := 6
+		record.WinningNumber = int(int8(buf[at]))
+		if wheels > 1 {
+			record.WinningNumbers = make([]int, wheels)
+			for k := range record.WinningNumbers {
+				record.WinningNumbers[k] = int(int8(buf[at+k]))
+			}
+		}
+		at += wheels
+		record.NetChange = FromCents(int64(binary.LittleEndian.Uint64(buf[at:])))
+		record.Bankroll = FromCents(int64(binary.LittleEndian.Uint64(buf[at+8:])))
+		at += 16
+		for j := range record.Stakes {
+			record.Stakes[j] = FromCents(int64(binary.LittleEndian.Uint64(buf[at:])))
+			at += 8
+		}
+		for j := 0; j < bets; j++ {
+			if buf[at+j/8]&(1<<(j%8)) != 0 {
+				record.Skipped = append(record.Skipped, j)
+			}
+		}
+		records = append(records, record)
+	}
+}
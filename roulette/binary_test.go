@@ -0,0 +1,138 @@
+package roulette
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinarySpinLogRoundTrip(t *testing.T) {
+	target := DoubleZero
+	zero := 0
+	records := []SpinRecord{
+		{
+			Spin: 1, WinningNumber: DoubleZero, WinningNumbers: []int{DoubleZero, 36},
+			Stakes:    []float64{10, 0, 2.5, 0, 0, 0, 0, 0, 0, 1234567.89},
+			Skipped:   []int{1, 3, 4, 5, 6, 7, 8},
+			Target:    &target,
+			NetChange: -1234580.39, Bankroll: -0.01,
+		},
+		{
+			Spin: 2, WinningNumber: 0, WinningNumbers: []int{0, 17},
+			Stakes:    make([]float64, 10),
+			Target:    &zero,
+			Spectated: true,
+			Bankroll:  99.99,
+		},
+		{
+			Spin: 1 << 20, WinningNumber: 17, WinningNumbers: []int{17, DoubleZero},
+			Stakes:    []float64{0.01, 0.02, 0.03, 0.04, 0.05, 0.06, 0.07, 0.08, 0.09, 0.10},
+			NetChange: 350, Bankroll: 1e10,
+		},
+	}
+	var buf bytes.Buffer
+	w, err := newBinarySpinWriter(&buf, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := w.write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := 10 + len(records)*spinRecordSize(10, 2); buf.Len() != want {
+		t.Errorf("log is %d bytes, want %d", buf.Len(), want)
+	}
+
+	got, err := ReadSpinLogBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("read back\n%+v\nwant\n%+v", got, records)
+	}
+}
+
+func TestBinarySpinLogMatchesSimulation(t *testing.T) {
+	// Nine bets on two wheels, with an adaptive target and a bankroll too
+	// small to cover them all
+	text := "bankroll: 100\nwheels: 2\nadaptive: hot\nwindow: 5\nbet: number, 17, 1\n" +
+		strings.Repeat("bet: red, 0, 5\n", 7) + "bet: split, 00 2, 1\n"
+	strategy := mustParse(t, text)
+	spins := []int{DoubleZero, 2, 1, 3, 17, 0, 4, 5, DoubleZero, DoubleZero, 19, 21}
+
+	var buf bytes.Buffer
+	if _, err := ReplayWithOptions(strategy, spins, SimulationOptions{SpinSink: &buf, SpinFormat: "binary"}); err != nil {
+		t.Fatal(err)
+	}
+	logged, err := ReplayWithOptions(strategy, spins, SimulationOptions{RecordSpins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSpinLogBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, logged.SpinLog) {
+		t.Errorf("read back\n%+v\nwant the in-memory log\n%+v", got, logged.SpinLog)
+	}
+
+	// The log covers what the test is meant to: losses, skipped bets past
+	// the first byte of the mask and a target
+	var loss, highSkip, targeted bool
+	for _, record := range got {
+		loss = loss || record.NetChange < 0
+		targeted = targeted || record.Target != nil
+		for _, j := range record.Skipped {
+			highSkip = highSkip || j >= 8
+		}
+	}
+	if !loss || !highSkip || !targeted {
+		t.Errorf("log has a loss %v, a skipped ninth bet %v and a target %v, want all three", loss, highSkip, targeted)
+	}
+}
+
+func TestReadSpinLogBinaryErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newBinarySpinWriter(&buf, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.write(SpinRecord{Spin: 1, WinningNumber: 3, Stakes: []float64{5}, NetChange: 5, Bankroll: 105}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.write(SpinRecord{Spin: 2, Stakes: []float64{5, 5}}); err == nil {
+		t.Error("a record with two stakes fit a log of one bet")
+	}
+	w.flush()
+	log := buf.Bytes()
+
+	truncated := log[:len(log)-3]
+	records, err := ReadSpinLogBinary(bytes.NewReader(truncated))
+	if err == nil || !strings.Contains(err.Error(), "partway through record 1") || len(records) != 0 {
+		t.Errorf("truncated log: got %d records and error %v", len(records), err)
+	}
+
+	badMagic := append([]byte("XSPL"), log[4:]...)
+	if _, err := ReadSpinLogBinary(bytes.NewReader(badMagic)); err == nil {
+		t.Error("a log with the wrong magic was read")
+	}
+	badVersion := append([]byte{}, log...)
+	badVersion[4] = 9
+	if _, err := ReadSpinLogBinary(bytes.NewReader(badVersion)); err == nil || !strings.Contains(err.Error(), "version 9") {
+		t.Errorf("got error %v, want an unsupported version", err)
+	}
+	if _, err := ReadSpinLogBinary(bytes.NewReader(log[:6])); err == nil {
+		t.Error("a short header was read")
+	}
+
+	// A header with no records is an empty log
+	records, err = ReadSpinLogBinary(bytes.NewReader(log[:10]))
+	if err != nil || len(records) != 0 {
+		t.Errorf("empty log: got %d records and error %v", len(records), err)
+	}
+}
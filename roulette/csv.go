@@ -0,0 +1,93 @@
+package roulette
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WriteBankrollCSV writes the bankroll after every spin of a simulation run
+// with SimulationOptions.RecordSpins set, one row per spin
+func WriteBankrollCSV(w io.Writer, result *SimulationResult) error {
+	if len(result.SpinLog) != result.SpinsPlayed {
+		return fmt.Errorf("result has %d spins but only %d logged; enable RecordSpins", result.SpinsPlayed, len(result.SpinLog))
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"spin", "winning_number", "bankroll"}); err != nil {
+		return err
+	}
+	for _, record := range result.SpinLog {
+		row := []string{
+			strconv.Itoa(record.Spin),
+			PocketLabel(record.WinningNumber),
+			strconv.FormatFloat(record.Bankroll, 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// LoadSpinsCSV reads a sequence of winning numbers from CSV, one per row.
+// The first row may be a header of column labels, in which case a
+// winning_number column is used if present and the first column otherwise.
+// A first row holding a number is always read as a spin, so an out-of-range
+// pocket there is an error rather than a header. 00 is written as "00".
+func LoadSpinsCSV(r io.Reader) ([]int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	var spins []int
+	column := 0
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if column >= len(record) {
+			return nil, fmt.Errorf("missing winning number on row %d", row)
+		}
+		value := strings.TrimSpace(record[column])
+		n, err := parsePocket(value)
+		if err != nil {
+			if row == 1 && isHeaderLabel(value) {
+				for i, label := range record {
+					if strings.TrimSpace(label) == "winning_number" {
+						column = i
+					}
+				}
+				continue
+			}
+			return nil, fmt.Errorf("%v on row %d", err, row)
+		}
+		spins = append(spins, n)
+	}
+	return spins, nil
+}
+
+// isHeaderLabel reports whether a CSV field reads as a column label rather
+// than a number: it starts with a letter, as in winning_number or spin
+func isHeaderLabel(field string) bool {
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsLetter(r)
+}
+
+// parsePocket parses a pocket label, reading "00" as the double zero
+func parsePocket(label string) (int, error) {
+	if label == "00" {
+		return DoubleZero, nil
+	}
+	n, err := strconv.Atoi(label)
+	if err != nil || n < 0 || !isPocket(n) {
+		return 0, fmt.Errorf("invalid winning number %q", label)
+	}
+	return n, nil
+}
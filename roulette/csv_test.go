@@ -0,0 +1,83 @@
+package roulette
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteBankrollCSV(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 300\nbet: red, 0, 10\nbet: number, 00, 5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := SimulateWithOptions(strategy, SimulationOptions{NumGames: 80, Seed: 4, RecordSpins: true})
+	var b strings.Builder
+	if err := WriteBankrollCSV(&b, result); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rows[0], ","); got != "spin,winning_number,bankroll" {
+		t.Errorf("header = %s", got)
+	}
+	rows = rows[1:]
+	if len(rows) != result.SpinsPlayed {
+		t.Fatalf("wrote %d rows, want %d", len(rows), result.SpinsPlayed)
+	}
+	last, err := strconv.ParseFloat(rows[len(rows)-1][2], 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != result.FinalBankroll {
+		t.Errorf("last row's bankroll = %v, want %v", last, result.FinalBankroll)
+	}
+
+	unlogged := SimulateWithOptions(strategy, SimulationOptions{NumGames: 80, Seed: 4})
+	if err := WriteBankrollCSV(&b, unlogged); err == nil {
+		t.Error("wrote a run without a spin log")
+	}
+}
+
+func TestLoadSpinsCSV(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []int
+	}{
+		{"5\n00\n0\n36\n", []int{5, DoubleZero, 0, 36}},
+		{"winning_number\n17\n00\n", []int{17, DoubleZero}},
+		{"spin,winning_number,bankroll\n1,00,90\n2,14,100\n", []int{DoubleZero, 14}},
+		{"spins\n3\n", []int{3}},
+	}
+	for _, tt := range tests {
+		got, err := LoadSpinsCSV(strings.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("%q: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: spins = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	errorTests := []struct {
+		input string
+		want  string
+	}{
+		{"5\nseven\n", `invalid winning number "seven" on row 2`},
+		{"5\n37\n", `invalid winning number "37" on row 2`},
+		{"99\n5\n", `invalid winning number "99" on row 1`},
+		{"-1\n", `invalid winning number "-1" on row 1`},
+		{"spin,winning_number\n1\n", "missing winning number on row 2"},
+	}
+	for _, tt := range errorTests {
+		_, err := LoadSpinsCSV(strings.NewReader(tt.input))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%q: got error %v, want %s", tt.input, err, tt.want)
+		}
+	}
+}
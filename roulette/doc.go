@@ -0,0 +1,5 @@
+// Package roulette simulates betting strategies at the roulette table. A
+// Strategy is usually read from the plain text format handled by
+// ParseStrategy, then played with SimulateRoulette for a single session or
+// RunMonteCarlo for many.
+package roulette
@@ -0,0 +1,33 @@
+package roulette_test
+
+import (
+	"fmt"
+
+	"github.com/steezeburger/roulette-simulator/roulette"
+)
+
+// Example_simulate parses a strategy and plays it against a known sequence
+// of spins, so the result is the same every time
+func Example_simulate() {
+	strategy, err := roulette.ParseStrategy(`
+bankroll: 100
+bet: red, 0, 10
+bet: number, 17, 5
+`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	result, err := roulette.SimulateWithSpins(strategy, []int{1, 17, 0, 3, roulette.DoubleZero})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Final bankroll after %d spins: %s\n", result.SpinsPlayed, roulette.FormatMoney(roulette.ToCents(result.FinalBankroll)))
+	fmt.Printf("Bets won/lost: %d/%d\n", result.BetsWon, result.BetsLost)
+	fmt.Printf("Longest losing streak: %d\n", result.LongestLossStreak)
+	// Output:
+	// Final bankroll after 5 spins: $245.00
+	// Bets won/lost: 3/7
+	// Longest losing streak: 1
+}
@@ -0,0 +1,102 @@
+package roulette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonStrategy is the document read by ParseStrategyJSON
+type jsonStrategy struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Bankroll    float64   `json:"bankroll"`
+	Wheel       string    `json:"wheel"`
+	Progression string    `json:"progression"`
+	Currency    string    `json:"currency"`
+	Precision   *int      `json:"precision"`
+	Bets        []jsonBet `json:"bets"`
+}
+
+// jsonBet is one bet of a JSON strategy. Value and Amount take the same forms
+// as on a DSL bet line, so they may be numbers or strings such as "00" and
+// "5%", and Value may also be a list of numbers.
+type jsonBet struct {
+	Type   string          `json:"type"`
+	Value  json.RawMessage `json:"value"`
+	Amount json.RawMessage `json:"amount"`
+}
+
+// ParseStrategyJSON reads a strategy from a JSON document such as
+//
+//	{"bankroll": 1000, "wheel": "european", "bets": [{"type": "red", "amount": 10}]}
+//
+// Each field is handed to the DSL parser as the line it would be in a DSL
+// strategy, so the two formats accept the same strategies.
+func ParseStrategyJSON(r io.Reader) (*Strategy, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var doc jsonStrategy
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON strategy: %v", err)
+	}
+
+	p := &strategyParser{strategy: &Strategy{}}
+	lines := []string{"bankroll: " + strconv.FormatFloat(doc.Bankroll, 'f', -1, 64)}
+	if doc.Name != "" {
+		lines = append(lines, "name: "+doc.Name)
+	}
+	if doc.Description != "" {
+		lines = append(lines, "description: "+doc.Description)
+	}
+	if doc.Wheel != "" {
+		lines = append(lines, "wheel: "+doc.Wheel)
+	}
+	if doc.Progression != "" {
+		lines = append(lines, "progression: "+doc.Progression)
+	}
+	if doc.Currency != "" {
+		lines = append(lines, "currency: "+doc.Currency)
+	}
+	if doc.Precision != nil {
+		lines = append(lines, "precision: "+strconv.Itoa(*doc.Precision))
+	}
+	for _, line := range lines {
+		if err := p.parseLine(line); err != nil {
+			return nil, err
+		}
+	}
+	for i, b := range doc.Bets {
+		line := fmt.Sprintf("bet: %s, %s, %s", b.Type, jsonToken(b.Value), jsonToken(b.Amount))
+		if err := p.parseLine(line); err != nil {
+			return nil, fmt.Errorf("bet %d: %v", i+1, err)
+		}
+	}
+	return p.finish()
+}
+
+// jsonToken turns a JSON value into the text a DSL bet line would hold in its
+// place: strings lose their quotes, lists are joined with spaces, a missing
+// value reads as 0, and anything else is used as written
+func jsonToken(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "0"
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		tokens := make([]string, len(list))
+		for i, item := range list {
+			tokens[i] = jsonToken(item)
+		}
+		return strings.Join(tokens, " ")
+	}
+	return string(raw)
+}
@@ -0,0 +1,87 @@
+package roulette
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStrategyJSON(t *testing.T) {
+	strategy, err := ParseStrategyJSON(strings.NewReader(`{
+		"name": "mixed",
+		"bankroll": 1000,
+		"wheel": "american",
+		"progression": "martingale",
+		"bets": [
+			{"type": "red", "amount": 10},
+			{"type": "number", "value": "00", "amount": "5%"},
+			{"type": "split", "value": [5, 8], "amount": 2.5}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strategy.Name != "mixed" || strategy.InitialBankroll != 1000 || strategy.Wheel != American || strategy.Progression != "martingale" {
+		t.Errorf("strategy = %+v", strategy)
+	}
+	want := []Bet{
+		{Type: "red", Amount: 10},
+		{Type: "number", Value: DoubleZero, Percent: 5},
+		{Type: "split", Values: []int{5, 8}, Amount: 2.5},
+	}
+	if !reflect.DeepEqual(strategy.Bets, want) {
+		t.Errorf("bets = %+v, want %+v", strategy.Bets, want)
+	}
+}
+
+func TestParseStrategyJSONErrors(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{`{"bankroll": 100, "bets": [{"type": "purple", "amount": 10}]}`, "bet 1: unknown bet type: purple"},
+		{`{"bankroll": 100, "bets": [{"type": "red", "amount": 10}, {"type": "dozen", "value": 4, "amount": 10}]}`, "bet 2: invalid dozen 4"},
+		{`{"bankroll": 100, "wheel": "european", "bets": [{"type": "number", "value": "00", "amount": 10}]}`, "00 is not a pocket"},
+		{`{"bankroll": 100, "wheel": "european", "bets": [{"type": "basket", "amount": 10}]}`, "basket bets need an american wheel"},
+		{`{"bankroll": 100, "progression": "hope", "bets": [{"type": "red", "amount": 10}]}`, "unknown progression: hope"},
+		{`{"bankroll": 100, "colour": "red"}`, "invalid JSON strategy"},
+		{`{"bankroll": -100}`, "bankroll must be positive"},
+		{`{"bankroll": 100,`, "invalid JSON strategy"},
+	}
+	for _, tt := range tests {
+		_, err := ParseStrategyJSON(strings.NewReader(tt.doc))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+}
+
+func TestNameAndDescription(t *testing.T) {
+	dsl := mustParse(t, "name: Safe and slow\ndescription: Flat red bets, for comparison\nbankroll: 100\nbet: red, 0, 5\n")
+	if dsl.Name != "Safe and slow" || dsl.Description != "Flat red bets, for comparison" {
+		t.Errorf("name %q and description %q", dsl.Name, dsl.Description)
+	}
+	fromJSON, err := ParseStrategyJSON(strings.NewReader(`{"name": "Safe and slow", "description": "Flat red bets, for comparison", "bankroll": 100, "bets": [{"type": "red", "amount": 5}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dsl, fromJSON) {
+		t.Errorf("JSON strategy = %+v, want %+v", fromJSON, dsl)
+	}
+
+	data, err := json.Marshal(dsl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Strategy
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Name != dsl.Name || back.Description != dsl.Description {
+		t.Errorf("came back from JSON as %q, %q", back.Name, back.Description)
+	}
+	if !strings.HasPrefix(dsl.String(), "Safe and slow: ") {
+		t.Errorf("String() = %q, want it to lead with the name", dsl.String())
+	}
+}
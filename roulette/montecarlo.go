@@ -0,0 +1,336 @@
+package roulette
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// MonteCarloResult aggregates the outcomes of many independent simulations
+type MonteCarloResult struct {
+	Runs             int               `json:"runs"`
+	Seed             int64             `json:"seed"`            // Seed of the first run; run i is seeded with Seed+i
+	FinalBankrolls   []float64         `json:"final_bankrolls"` // Final bankroll of every run, sorted ascending
+	Mean             float64           `json:"mean"`
+	StdDev           float64           `json:"std_dev"`
+	MeanCILow        float64           `json:"mean_ci_low"`  // Lower end of the 95% confidence interval for Mean
+	MeanCIHigh       float64           `json:"mean_ci_high"` // Upper end of the 95% confidence interval for Mean
+	Median           float64           `json:"median"`
+	Min              float64           `json:"min"`
+	Max              float64           `json:"max"`
+	ProfitPercent    float64           `json:"profit_percent"`    // Percentage of runs that ended above the initial bankroll
+	BustPercent      float64           `json:"bust_percent"`      // Percentage of runs that went bust
+	BustSpins        []int             `json:"bust_spins"`        // Spin each bust run went bust on, sorted ascending
+	RTP              float64           `json:"rtp"`               // Return to player across every run, weighting each run by how much it wagered
+	HouseProfit      float64           `json:"house_profit"`      // What the casino won across every run put together
+	Outcomes         map[Outcome]int   `json:"outcomes"`          // Number of runs that ended each way
+	BetContributions []BetContribution `json:"bet_contributions"` // How each bet drove the results, in strategy order
+}
+
+// RunMonteCarlo runs numRuns independent simulations of numGames spins each
+// and aggregates their final bankrolls
+func RunMonteCarlo(strategy *Strategy, numGames, numRuns int) *MonteCarloResult {
+	return RunMonteCarloSeeded(strategy, numGames, numRuns, newSeed())
+}
+
+// RunMonteCarloSeeded is like RunMonteCarlo but derives every run's seed from
+// seed, so the aggregate is reproducible
+func RunMonteCarloSeeded(strategy *Strategy, numGames, numRuns int, seed int64) *MonteCarloResult {
+	return runMonteCarlo(strategy, numGames, numRuns, seed, runtime.NumCPU())
+}
+
+// runMonteCarlo spreads the runs across a pool of workers. Run i is always
+// seeded with seed+i and its result stored at index i, so the aggregate
+// doesn't depend on the number of workers or the order runs complete in.
+func runMonteCarlo(strategy *Strategy, numGames, numRuns int, seed int64, workers int) *MonteCarloResult {
+	mc := &MonteCarloResult{Runs: numRuns, Seed: seed, Outcomes: make(map[Outcome]int)}
+	if numRuns <= 0 {
+		return mc
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	type runResult struct {
+		index  int
+		result *SimulationResult
+	}
+	jobs := make(chan int)
+	results := make(chan runResult)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results <- runResult{index: i, result: SimulateSeeded(strategy, numGames, seed+int64(i))}
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < numRuns; i++ {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	runs := make([]*SimulationResult, numRuns)
+	for r := range results {
+		runs[r.index] = r.result
+	}
+
+	profitable, bust := 0, 0
+	sum, wagered, returned := 0.0, 0.0, 0.0
+	var houseProfit int64
+	for _, result := range runs {
+		mc.FinalBankrolls = append(mc.FinalBankrolls, result.FinalBankroll)
+		sum += result.FinalBankroll
+		wagered += result.TotalWagered
+		returned += result.TotalReturned
+		houseProfit += ToCents(result.HouseProfit)
+		mc.Outcomes[result.Outcome]++
+		if result.FinalBankroll > strategy.InitialBankroll {
+			profitable++
+		}
+		if result.WentBust {
+			bust++
+			mc.BustSpins = append(mc.BustSpins, result.BustSpin)
+		}
+	}
+	sort.Float64s(mc.FinalBankrolls)
+	sort.Ints(mc.BustSpins)
+
+	mc.Mean = sum / float64(numRuns)
+	mc.HouseProfit = FromCents(houseProfit)
+	if wagered > 0 {
+		mc.RTP = 100 * returned / wagered
+	}
+	variance := 0.0
+	for _, b := range mc.FinalBankrolls {
+		variance += (b - mc.Mean) * (b - mc.Mean)
+	}
+	mc.StdDev = math.Sqrt(variance / float64(numRuns))
+	// The interval uses the sample standard deviation. A single run says
+	// nothing about the spread, so its interval collapses onto the mean.
+	mc.MeanCILow, mc.MeanCIHigh = mc.Mean, mc.Mean
+	if numRuns > 1 {
+		margin := 1.96 * math.Sqrt(variance/float64(numRuns-1)) / math.Sqrt(float64(numRuns))
+		mc.MeanCILow, mc.MeanCIHigh = mc.Mean-margin, mc.Mean+margin
+	}
+	mc.Min = mc.FinalBankrolls[0]
+	mc.Max = mc.FinalBankrolls[numRuns-1]
+	if numRuns%2 == 1 {
+		mc.Median = mc.FinalBankrolls[numRuns/2]
+	} else {
+		mc.Median = (mc.FinalBankrolls[numRuns/2-1] + mc.FinalBankrolls[numRuns/2]) / 2
+	}
+	mc.BetContributions = betContributions(strategy, runs)
+	mc.ProfitPercent = 100 * float64(profitable) / float64(numRuns)
+	mc.BustPercent = 100 * float64(bust) / float64(numRuns)
+	return mc
+}
+
+// BetContribution describes how one bet of a strategy drove the Monte Carlo
+// results. The variance shares of all the bets add up to 1, so the bet with
+// the largest share is the one that swings the outcome most.
+type BetContribution struct {
+	Bet           int     `json:"bet"`            // Index of the bet in the strategy
+	MeanProfit    float64 `json:"mean_profit"`    // Average net profit of the bet per run
+	StdDev        float64 `json:"std_dev"`        // Spread of the bet's net profit across runs
+	Correlation   float64 `json:"correlation"`    // Correlation of the bet's profit with the run's total profit
+	VarianceShare float64 `json:"variance_share"` // Fraction of the variance in total profit the bet accounts for
+}
+
+// betContributions works out each bet's BetContribution from the per-bet
+// profits of every run
+func betContributions(strategy *Strategy, runs []*SimulationResult) []BetContribution {
+	n := float64(len(runs))
+	totals := make([]float64, len(runs))
+	meanTotal := 0.0
+	for i, result := range runs {
+		totals[i] = result.FinalBankroll - strategy.InitialBankroll
+		meanTotal += totals[i] / n
+	}
+	varTotal := 0.0
+	for _, t := range totals {
+		varTotal += (t - meanTotal) * (t - meanTotal) / n
+	}
+
+	contributions := make([]BetContribution, len(strategy.Bets))
+	for j := range strategy.Bets {
+		c := BetContribution{Bet: j}
+		for _, result := range runs {
+			c.MeanProfit += result.PerBet[j].NetProfit / n
+		}
+		variance, covariance := 0.0, 0.0
+		for i, result := range runs {
+			d := result.PerBet[j].NetProfit - c.MeanProfit
+			variance += d * d / n
+			covariance += d * (totals[i] - meanTotal) / n
+		}
+		c.StdDev = math.Sqrt(variance)
+		if variance > 0 && varTotal > 0 {
+			c.Correlation = covariance / math.Sqrt(variance*varTotal)
+		}
+		if varTotal > 0 {
+			c.VarianceShare = covariance / varTotal
+		}
+		contributions[j] = c
+	}
+	return contributions
+}
+
+// Bucket is one bar of a histogram, counting the values in [Low, High). The
+// last bucket of a histogram also includes its High value.
+type Bucket struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Count int     `json:"count"`
+}
+
+// Percentile returns the p-th percentile of the final bankrolls, so 5 gives
+// the bankroll only the unluckiest 5% of runs fell below. Values between two
+// runs are interpolated, which makes Percentile(50) agree with Median, and p
+// is clamped to the range 0 to 100.
+func (m *MonteCarloResult) Percentile(p float64) float64 {
+	n := len(m.FinalBankrolls)
+	if n == 0 {
+		return 0
+	}
+	p = math.Max(0, math.Min(100, p))
+	pos := p / 100 * float64(n-1)
+	i := int(pos)
+	if i >= n-1 {
+		return m.FinalBankrolls[n-1]
+	}
+	frac := pos - float64(i)
+	return m.FinalBankrolls[i] + frac*(m.FinalBankrolls[i+1]-m.FinalBankrolls[i])
+}
+
+// RuinProbability returns the fraction of runs that went bust
+func (m *MonteCarloResult) RuinProbability() float64 {
+	if m.Runs == 0 {
+		return 0
+	}
+	return float64(len(m.BustSpins)) / float64(m.Runs)
+}
+
+// RuinByGames returns the fraction of runs that had gone bust within the
+// first games spins. Rising games from zero to the length of the runs traces
+// out how the risk of ruin builds up over a session.
+func (m *MonteCarloResult) RuinByGames(games int) float64 {
+	if m.Runs == 0 {
+		return 0
+	}
+	return float64(sort.SearchInts(m.BustSpins, games+1)) / float64(m.Runs)
+}
+
+// RuinProbabilityAnalytic works out, without simulating, the chance that flat
+// even-money bets of baseBet go bust within the given number of rounds,
+// starting from bankroll. Each spin moves the bankroll one bet up or down, so
+// by the hitting-time form of the gambler's-ruin problem the chance of first
+// falling below one bet on spin k is
+//
+//	(i/k) * C(k, (k+i)/2) * p^((k-i)/2) * q^((k+i)/2)
+//
+// for a bankroll of i bets, winning chance p and losing chance q, and summing
+// it over k gives the answer. It should agree with the RuinProbability of a
+// Monte Carlo run of the same strategy within sampling error. A bankroll or
+// bet that isn't positive returns NaN.
+func RuinProbabilityAnalytic(bankroll, baseBet float64, wheel WheelType, rounds int) float64 {
+	if !(bankroll > 0) || !(baseBet > 0) {
+		return math.NaN()
+	}
+	units := int(math.Floor(bankroll/baseBet + 1e-9))
+	if units == 0 {
+		return 1
+	}
+	p := WinProbability("red", wheel)
+	logP, logQ := math.Log(p), math.Log(1-p)
+	ruin := 0.0
+	for k := units; k <= rounds; k += 2 {
+		wins, losses := (k-units)/2, (k+units)/2
+		lgK, _ := math.Lgamma(float64(k + 1))
+		lgW, _ := math.Lgamma(float64(wins + 1))
+		lgL, _ := math.Lgamma(float64(losses + 1))
+		logTerm := math.Log(float64(units)/float64(k)) + lgK - lgW - lgL + float64(wins)*logP + float64(losses)*logQ
+		ruin += math.Exp(logTerm)
+	}
+	return math.Min(ruin, 1)
+}
+
+// Histogram splits the range of final bankrolls into evenly spaced buckets
+// and counts the runs that landed in each. When every run finished with the
+// same bankroll there is no range to split, so a single bucket is returned.
+func (m *MonteCarloResult) Histogram(buckets int) []Bucket {
+	if len(m.FinalBankrolls) == 0 || buckets < 1 {
+		return nil
+	}
+	if m.Max == m.Min {
+		return []Bucket{{Low: m.Min, High: m.Max, Count: len(m.FinalBankrolls)}}
+	}
+
+	width := (m.Max - m.Min) / float64(buckets)
+	histogram := make([]Bucket, buckets)
+	for i := range histogram {
+		histogram[i].Low = m.Min + float64(i)*width
+		histogram[i].High = m.Min + float64(i+1)*width
+	}
+	histogram[buckets-1].High = m.Max
+	for _, b := range m.FinalBankrolls {
+		i := int((b - m.Min) / width)
+		if i >= buckets {
+			i = buckets - 1
+		}
+		histogram[i].Count++
+	}
+	return histogram
+}
+
+// StrategyComparison summarizes one strategy's Monte Carlo results so it can
+// be ranked against others
+type StrategyComparison struct {
+	Name        string  `json:"name"`
+	Mean        float64 `json:"mean"`
+	StdDev      float64 `json:"std_dev"`
+	BustPercent float64 `json:"bust_percent"`
+}
+
+// CompareStrategies runs Monte Carlo simulations of every strategy and returns
+// them ranked by mean final bankroll, best first
+func CompareStrategies(strategies map[string]*Strategy, numGames, numRuns int) []StrategyComparison {
+	return CompareStrategiesSeeded(strategies, numGames, numRuns, newSeed())
+}
+
+// CompareStrategiesSeeded is like CompareStrategies but derives every run's
+// seed from seed, so the ranking is reproducible. The strategies are taken in
+// order of name, and the i-th is run from seed+i*numRuns, so each one gets
+// its own stream of spins from the shared base.
+func CompareStrategiesSeeded(strategies map[string]*Strategy, numGames, numRuns int, seed int64) []StrategyComparison {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	comparisons := make([]StrategyComparison, 0, len(strategies))
+	for i, name := range names {
+		strategy := strategies[name]
+		mc := RunMonteCarloSeeded(strategy, numGames, numRuns, seed+int64(i)*int64(numRuns))
+		comparisons = append(comparisons, StrategyComparison{
+			Name:        name,
+			Mean:        mc.Mean,
+			StdDev:      mc.StdDev,
+			BustPercent: mc.BustPercent,
+		})
+	}
+	sort.Slice(comparisons, func(i, j int) bool {
+		if comparisons[i].Mean != comparisons[j].Mean {
+			return comparisons[i].Mean > comparisons[j].Mean
+		}
+		return comparisons[i].Name < comparisons[j].Name
+	})
+	return comparisons
+}
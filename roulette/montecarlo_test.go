@@ -0,0 +1,298 @@
+package roulette
+
+import (
+	"math"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestMonteCarloMeanConverges(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 10000\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := RunMonteCarlo(strategy, 100, 2000)
+	want := 10000 - 100*10*2.0/38
+	if math.Abs(result.Mean-want) > 10 {
+		t.Errorf("mean final bankroll = %.2f, want about %.2f", result.Mean, want)
+	}
+	if result.Min == result.Max {
+		t.Error("every run ended with the same bankroll, so the runs weren't independent")
+	}
+	if result.Min > result.Median || result.Median > result.Max {
+		t.Errorf("median %v is outside min %v and max %v", result.Median, result.Min, result.Max)
+	}
+	if result.StdDev <= 0 || result.ProfitPercent <= 0 || result.ProfitPercent >= 100 {
+		t.Errorf("std dev %v and profit %v%% don't look like independent runs", result.StdDev, result.ProfitPercent)
+	}
+}
+
+func TestMonteCarloWorkersAgree(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 500\nprogression: martingale\nbet: red, 0, 5\nbet: number, 7, 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial := runMonteCarlo(strategy, 200, 300, 11, 1)
+	for _, workers := range []int{2, 8} {
+		parallel := runMonteCarlo(strategy, 200, 300, 11, workers)
+		if !reflect.DeepEqual(serial, parallel) {
+			t.Errorf("%d workers gave a different result than 1", workers)
+		}
+	}
+}
+
+func benchmarkMonteCarlo(b *testing.B, workers int) {
+	strategy, err := ParseStrategy("bankroll: 1000\nbet: red, 0, 10\n")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		runMonteCarlo(strategy, 1000, 200, 1, workers)
+	}
+}
+
+func BenchmarkMonteCarloSerial(b *testing.B) {
+	benchmarkMonteCarlo(b, 1)
+}
+
+func BenchmarkMonteCarloParallel(b *testing.B) {
+	benchmarkMonteCarlo(b, runtime.NumCPU())
+}
+
+func TestCompareStrategiesRanksByEdge(t *testing.T) {
+	strategies, err := ParseStrategies("strategy: basket\nbankroll: 10000\nbet: basket, 0, 10\n\nstrategy: european\nbankroll: 10000\nwheel: european\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparisons := CompareStrategiesSeeded(strategies, 1000, 200, 17)
+	if len(comparisons) != 2 || comparisons[0].Name != "european" || comparisons[1].Name != "basket" {
+		t.Fatalf("ranking = %+v, want european ahead of basket", comparisons)
+	}
+	if again := CompareStrategiesSeeded(strategies, 1000, 200, 17); !reflect.DeepEqual(comparisons, again) {
+		t.Errorf("the same seed ranked %+v, then %+v", comparisons, again)
+	}
+}
+
+func TestCompareStrategiesGivesEachItsOwnStream(t *testing.T) {
+	strategies, err := ParseStrategies("strategy: a\nbankroll: 1000\nbet: red, 0, 10\n\nstrategy: b\nbankroll: 1000\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparisons := CompareStrategiesSeeded(strategies, 100, 50, 17)
+	if comparisons[0].Mean == comparisons[1].Mean && comparisons[0].StdDev == comparisons[1].StdDev {
+		t.Error("two copies of a strategy were played on the same spins")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	mc := &MonteCarloResult{FinalBankrolls: []float64{0, 10, 20, 25, 30, 40}, Min: 0, Max: 40}
+	histogram := mc.Histogram(4)
+	want := []Bucket{
+		{Low: 0, High: 10, Count: 1},
+		{Low: 10, High: 20, Count: 1},
+		{Low: 20, High: 30, Count: 2},
+		{Low: 30, High: 40, Count: 2},
+	}
+	if !reflect.DeepEqual(histogram, want) {
+		t.Errorf("histogram = %+v, want %+v", histogram, want)
+	}
+
+	flat := &MonteCarloResult{FinalBankrolls: []float64{50, 50, 50}, Min: 50, Max: 50}
+	if got := flat.Histogram(10); !reflect.DeepEqual(got, []Bucket{{Low: 50, High: 50, Count: 3}}) {
+		t.Errorf("zero-width histogram = %+v, want a single bucket of 3", got)
+	}
+
+	strategy, err := ParseStrategy("bankroll: 1000\nbet: number, 17, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := RunMonteCarloSeeded(strategy, 100, 321, 2)
+	total := 0
+	for _, bucket := range result.Histogram(7) {
+		total += bucket.Count
+	}
+	if total != 321 {
+		t.Errorf("bucket counts add up to %d, want 321", total)
+	}
+}
+
+func TestMeanConfidenceInterval(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 10000\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	few := RunMonteCarloSeeded(strategy, 100, 100, 1)
+	many := RunMonteCarloSeeded(strategy, 100, 1600, 1)
+	if many.MeanCIHigh-many.MeanCILow >= few.MeanCIHigh-few.MeanCILow {
+		t.Errorf("interval is %v wide over 1600 runs, not narrower than %v over 100",
+			many.MeanCIHigh-many.MeanCILow, few.MeanCIHigh-few.MeanCILow)
+	}
+
+	want := 10000 - 100*10*2.0/38
+	bracketed := 0
+	for seed := int64(0); seed < 40; seed++ {
+		mc := RunMonteCarloSeeded(strategy, 100, 100, seed*1000)
+		if mc.MeanCILow <= want && want <= mc.MeanCIHigh {
+			bracketed++
+		}
+	}
+	if bracketed < 34 {
+		t.Errorf("%d of 40 intervals held the expected mean, want about 38", bracketed)
+	}
+
+	single := RunMonteCarloSeeded(strategy, 100, 1, 1)
+	if single.MeanCILow != single.Mean || single.MeanCIHigh != single.Mean {
+		t.Errorf("single run has an interval of %v to %v around %v", single.MeanCILow, single.MeanCIHigh, single.Mean)
+	}
+}
+
+func TestRuinProbability(t *testing.T) {
+	allIn := RunMonteCarloSeeded(mustParse(t, "bankroll: 100\nbet: number, 17, 100\n"), 10, 500, 68)
+	if ruin := allIn.RuinProbability(); ruin < 0.9 {
+		t.Errorf("all in on a number went bust %.1f%% of the time, want almost always", 100*ruin)
+	}
+	tiny := RunMonteCarloSeeded(mustParse(t, "bankroll: 10000\nbet: red, 0, 1\n"), 500, 500, 68)
+	if ruin := tiny.RuinProbability(); ruin != 0 {
+		t.Errorf("tiny stakes went bust %.1f%% of the time, want never", 100*ruin)
+	}
+
+	// Going all in, a run can only go bust on its first spin
+	if first := allIn.RuinByGames(1); first != allIn.RuinProbability() || allIn.RuinByGames(0) != 0 {
+		t.Errorf("ruin by spin 0 = %v and by spin 1 = %v, want 0 and %v", allIn.RuinByGames(0), first, allIn.RuinProbability())
+	}
+
+	mixed := RunMonteCarloSeeded(mustParse(t, "bankroll: 50\nbet: red, 0, 10\n"), 200, 500, 68)
+	previous := 0.0
+	for games := 0; games <= 200; games += 10 {
+		ruin := mixed.RuinByGames(games)
+		if ruin < previous {
+			t.Errorf("ruin by spin %d = %v, below %v earlier", games, ruin, previous)
+		}
+		previous = ruin
+	}
+	if previous != mixed.RuinProbability() {
+		t.Errorf("ruin by the last spin = %v, want %v", previous, mixed.RuinProbability())
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	mc := &MonteCarloResult{FinalBankrolls: []float64{10, 20, 30, 40, 50}}
+	for _, tt := range []struct{ p, want float64 }{
+		{0, 10}, {10, 14}, {25, 20}, {50, 30}, {75, 40}, {95, 48}, {100, 50}, {-5, 10}, {150, 50},
+	} {
+		if got := mc.Percentile(tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := (&MonteCarloResult{}).Percentile(50); got != 0 {
+		t.Errorf("Percentile of no runs = %v, want 0", got)
+	}
+
+	run := RunMonteCarloSeeded(mustParse(t, "bankroll: 1000\nbet: red, 0, 10\n"), 100, 101, 77)
+	if run.Percentile(50) != run.Median {
+		t.Errorf("Percentile(50) = %v, want the median %v", run.Percentile(50), run.Median)
+	}
+	if run.Percentile(5) > run.Percentile(95) {
+		t.Errorf("5th percentile %v is above the 95th %v", run.Percentile(5), run.Percentile(95))
+	}
+}
+
+func TestBetContributions(t *testing.T) {
+	// A straight-up bet swings far more than a small even-money one
+	strategy, err := ParseStrategy("bankroll: 100000\nbet: red, 0, 1\nbet: number, 17, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := RunMonteCarloSeeded(strategy, 100, 500, 5)
+	if len(result.BetContributions) != 2 {
+		t.Fatalf("got %d contributions, want 2", len(result.BetContributions))
+	}
+	red, number := result.BetContributions[0], result.BetContributions[1]
+	if red.Bet != 0 || number.Bet != 1 {
+		t.Errorf("contributions are for bets %d and %d, want 0 and 1", red.Bet, number.Bet)
+	}
+	if number.VarianceShare <= red.VarianceShare || number.StdDev <= red.StdDev {
+		t.Errorf("number bet share %v (spread %v) doesn't beat red's %v (spread %v)",
+			number.VarianceShare, number.StdDev, red.VarianceShare, red.StdDev)
+	}
+	if number.Correlation < 0.9 {
+		t.Errorf("number bet correlation = %v, want close to 1", number.Correlation)
+	}
+	if share := red.VarianceShare + number.VarianceShare; math.Abs(share-1) > 1e-9 {
+		t.Errorf("variance shares add up to %v, want 1", share)
+	}
+	meanProfit := result.Mean - strategy.InitialBankroll
+	if sum := red.MeanProfit + number.MeanProfit; math.Abs(sum-meanProfit) > 1e-6 {
+		t.Errorf("bet mean profits add up to %v, want the mean profit %v", sum, meanProfit)
+	}
+}
+
+func TestHouseProfitMirrorsPlayerLoss(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 500\nhouse_bankroll: 100000\nbet: red, 0, 5\nbet: number, 00, 0.10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strategy.HouseBankroll != 100000 {
+		t.Errorf("HouseBankroll = %v, want 100000", strategy.HouseBankroll)
+	}
+	result := RunMonteCarloSeeded(strategy, 200, 300, 9)
+	var playerNet int64
+	for _, b := range result.FinalBankrolls {
+		playerNet += ToCents(b) - ToCents(strategy.InitialBankroll)
+	}
+	if ToCents(result.HouseProfit) != -playerNet {
+		t.Errorf("house profit = %v, want the player's net loss %v", result.HouseProfit, FromCents(-playerNet))
+	}
+
+	run := SimulateSeeded(strategy, 200, 9)
+	if want := ToCents(strategy.InitialBankroll) - ToCents(run.FinalBankroll); ToCents(run.HouseProfit) != want {
+		t.Errorf("run house profit = %v, want %v", run.HouseProfit, FromCents(want))
+	}
+}
+
+func TestRuinProbabilityAnalyticMatchesMonteCarlo(t *testing.T) {
+	for _, tt := range []struct {
+		text     string
+		bankroll float64
+		bet      float64
+		wheel    WheelType
+		rounds   int
+	}{
+		{"bankroll: 50\nbet: red, 0, 10\n", 50, 10, American, 200},
+		{"bankroll: 55\nbet: red, 0, 10\n", 55, 10, American, 200},
+		{"bankroll: 100\nwheel: european\nbet: even, 0, 10\n", 100, 10, European, 300},
+		{"bankroll: 20\nbet: low, 0, 5\n", 20, 5, American, 25},
+	} {
+		analytic := RuinProbabilityAnalytic(tt.bankroll, tt.bet, tt.wheel, tt.rounds)
+		const runs = 10000
+		simulated := RunMonteCarloSeeded(mustParse(t, tt.text), tt.rounds, runs, 90).RuinProbability()
+		// Four standard errors of the simulated proportion
+		tolerance := 4 * math.Sqrt(analytic*(1-analytic)/runs)
+		if math.Abs(analytic-simulated) > tolerance {
+			t.Errorf("%q: analytic ruin %v, Monte Carlo %v, want within %v", tt.text, analytic, simulated, tolerance)
+		}
+	}
+}
+
+func TestRuinProbabilityAnalyticEdges(t *testing.T) {
+	for _, tt := range []struct{ bankroll, bet float64 }{{0, 10}, {-5, 10}, {100, 0}, {100, -1}, {math.NaN(), 10}} {
+		if got := RuinProbabilityAnalytic(tt.bankroll, tt.bet, American, 100); !math.IsNaN(got) {
+			t.Errorf("bankroll %v, bet %v: got %v, want NaN", tt.bankroll, tt.bet, got)
+		}
+	}
+	if got := RuinProbabilityAnalytic(5, 10, American, 100); got != 1 {
+		t.Errorf("a bankroll below one bet: got %v, want 1", got)
+	}
+	// Five bets can't all be lost in four spins
+	if got := RuinProbabilityAnalytic(50, 10, American, 4); got != 0 {
+		t.Errorf("four spins from five bets: got %v, want 0", got)
+	}
+	if got, want := RuinProbabilityAnalytic(10, 10, American, 1), 20.0/38; math.Abs(got-want) > 1e-12 {
+		t.Errorf("one bet for one spin: got %v, want %v", got, want)
+	}
+	if short, long := RuinProbabilityAnalytic(50, 10, American, 50), RuinProbabilityAnalytic(50, 10, American, 500); long <= short {
+		t.Errorf("ruin over 500 spins %v isn't above ruin over 50 %v", long, short)
+	}
+}
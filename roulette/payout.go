@@ -0,0 +1,134 @@
+package roulette
+
+// payoutMultiple returns how many times its stake a bet returns to the player,
+// stake included, when settled against the winning number, or 0 if it lost
+func (s *Strategy) payoutMultiple(bet Bet, winningNumber int) int64 {
+	if !betWins(bet, winningNumber) {
+		return 0
+	}
+	return int64(s.PayoutMultiple(bet.Type))
+}
+
+// betWins reports whether a bet wins when the given number comes up
+func betWins(bet Bet, winningNumber int) bool {
+	switch bet.Type {
+	case "number":
+		return bet.Value == winningNumber
+	case "even":
+		return winningNumber%2 == 0 && !IsGreen(winningNumber)
+	case "odd":
+		return winningNumber%2 != 0 && !IsGreen(winningNumber)
+	case "red":
+		return IsRed(winningNumber)
+	case "black":
+		return IsBlack(winningNumber)
+	case "dozen":
+		return !IsGreen(winningNumber) && (winningNumber-1)/12+1 == bet.Value
+	case "column":
+		// Column 3 holds the multiples of three, so it maps to remainder 0
+		columnRemainder := bet.Value % 3
+		return !IsGreen(winningNumber) && winningNumber%3 == columnRemainder
+	case "low":
+		return winningNumber >= 1 && winningNumber <= 18
+	case "high":
+		return winningNumber >= 19 && winningNumber <= 36
+	case "split", "corner", "trio":
+		return contains(bet.Values, winningNumber)
+	case "street":
+		return winningNumber >= bet.Value && winningNumber <= bet.Value+2
+	case "line":
+		return winningNumber >= bet.Value && winningNumber <= bet.Value+5
+	case "basket":
+		return IsGreen(winningNumber) || (winningNumber >= 1 && winningNumber <= 3)
+	}
+	return false
+}
+
+// betCoverage is how many pockets each bet type covers
+var betCoverage = map[string]int{
+	"number": 1,
+	"split":  2,
+	"street": 3,
+	"trio":   3,
+	"corner": 4,
+	"basket": 5,
+	"line":   6,
+	"dozen":  12,
+	"column": 12,
+	"red":    18,
+	"black":  18,
+	"even":   18,
+	"odd":    18,
+	"low":    18,
+	"high":   18,
+}
+
+// payoutMultiples is how many times its stake each winning bet type returns,
+// stake included
+var payoutMultiples = map[string]float64{
+	"number": 36, // 35:1
+	"split":  18, // 17:1
+	"street": 12, // 11:1
+	"trio":   12,
+	"corner": 9, // 8:1
+	"basket": 7, // 6:1
+	"line":   6, // 5:1
+	"dozen":  3, // 2:1
+	"column": 3,
+	"red":    2, // 1:1
+	"black":  2,
+	"even":   2,
+	"odd":    2,
+	"low":    2,
+	"high":   2,
+}
+
+// PayoutMultiple returns how many times its stake a winning bet of the given
+// type returns, stake included, so a straight-up number returns 36 for 35:1
+func PayoutMultiple(betType string) float64 {
+	return payoutMultiples[betType]
+}
+
+// PayoutMultiple is like the package-level PayoutMultiple but honours any
+// payout_ directives of the strategy, so "payout_number: 30" makes a
+// straight-up number return 31
+func (s *Strategy) PayoutMultiple(betType string) float64 {
+	if odds, ok := s.Payouts[betType]; ok {
+		return float64(odds + 1)
+	}
+	return PayoutMultiple(betType)
+}
+
+// WinProbability returns the chance that a bet of the given type wins on a
+// single spin of the given wheel
+func WinProbability(betType string, wheel WheelType) float64 {
+	if betType == "basket" && wheel != American {
+		return 0
+	}
+	return float64(betCoverage[betType]) / float64(len(NewWheel(wheel).Numbers))
+}
+
+// KellyFraction returns the fraction of the bankroll the Kelly criterion
+// would stake on a bet of the given type, paying payoutOverride to 1 or the
+// standard odds when payoutOverride is zero. Every standard bet has a house
+// edge, so the fraction comes out negative: the Kelly way of saying don't bet.
+func KellyFraction(betType string, wheel WheelType, payoutOverride float64) float64 {
+	odds := PayoutMultiple(betType) - 1
+	if payoutOverride > 0 {
+		odds = payoutOverride
+	}
+	p := WinProbability(betType, wheel)
+	if odds <= 0 || p == 0 {
+		return 0
+	}
+	return (odds*p - (1 - p)) / odds
+}
+
+// isEvenMoney reports whether a bet type pays 1 to 1
+func isEvenMoney(betType string) bool {
+	switch betType {
+	case "red", "black", "odd", "even", "low", "high":
+		return true
+	}
+	return false
+}
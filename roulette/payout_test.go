@@ -0,0 +1,259 @@
+package roulette
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// playOn plays a single spin of the strategy that lands on n and returns the
+// change in the bankroll, using the first seed whose opening spin is n.
+func playOn(t *testing.T, text string, n int) float64 {
+	t.Helper()
+	strategy, err := ParseStrategy(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for seed := int64(1); seed < 100_000; seed++ {
+		if newSeededWheel(strategy.Wheel, seed).Spin() == n {
+			return SimulateSeeded(strategy, 1, seed).FinalBankroll - strategy.InitialBankroll
+		}
+	}
+	t.Fatalf("no seed lands on %s", PocketLabel(n))
+	return 0
+}
+
+// checkCovers plays a 10 chip bet on every pocket of the American wheel and
+// checks that it wins odds to 1 on the covered numbers and loses elsewhere.
+func checkCovers(t *testing.T, bet string, covered []int, odds float64) {
+	t.Helper()
+	for _, n := range NewRouletteWheel().Numbers {
+		want := -10.0
+		if contains(covered, n) {
+			want = 10 * odds
+		}
+		if got := playOn(t, "bankroll: 100\nbet: "+bet+", 10\n", n); got != want {
+			t.Errorf("%s on %s: net %v, want %v", bet, PocketLabel(n), got, want)
+		}
+	}
+}
+
+func TestDozenBets(t *testing.T) {
+	tests := []struct {
+		dozen  int
+		number int
+		want   bool
+	}{
+		{1, 1, true},
+		{1, 7, true},
+		{1, 12, true},
+		{1, 13, false},
+		{2, 13, true},
+		{2, 18, true},
+		{2, 24, true},
+		{2, 12, false},
+		{2, 25, false},
+		{3, 25, true},
+		{3, 30, true},
+		{3, 36, true},
+		{3, 24, false},
+		{1, 0, false},
+		{2, DoubleZero, false},
+		{3, 0, false},
+	}
+	for _, tt := range tests {
+		// A winning dozen pays 2 to 1
+		want := -10.0
+		if tt.want {
+			want = 20
+		}
+		if got := playOn(t, "bankroll: 100\nbet: dozen, "+strconv.Itoa(tt.dozen)+", 10\n", tt.number); got != want {
+			t.Errorf("dozen %d on %s: net %v, want %v", tt.dozen, PocketLabel(tt.number), got, want)
+		}
+	}
+}
+
+func TestDozenValidation(t *testing.T) {
+	for _, value := range []string{"0", "4"} {
+		_, err := ParseStrategy("bankroll: 100\nbet: dozen, " + value + ", 10\n")
+		if err == nil || !strings.Contains(err.Error(), "invalid dozen") {
+			t.Errorf("dozen %s: got error %v, want an invalid dozen error", value, err)
+		}
+	}
+}
+
+func TestColumnBets(t *testing.T) {
+	for column := 1; column <= 3; column++ {
+		text := "bankroll: 100\nbet: column, " + strconv.Itoa(column) + ", 10\n"
+		for n := 1; n <= 36; n++ {
+			// A winning column pays 2 to 1
+			want := -10.0
+			if (n-1)%3+1 == column {
+				want = 20
+			}
+			if got := playOn(t, text, n); got != want {
+				t.Errorf("column %d on %d: net %v, want %v", column, n, got, want)
+			}
+		}
+		for _, n := range []int{0, DoubleZero} {
+			if got := playOn(t, text, n); got != -10 {
+				t.Errorf("column %d on %s: net %v, want -10", column, PocketLabel(n), got)
+			}
+		}
+	}
+	_, err := ParseStrategy("bankroll: 100\nbet: column, 4, 10\n")
+	if err == nil || !strings.Contains(err.Error(), "invalid column") {
+		t.Errorf("column 4: got error %v, want an invalid column error", err)
+	}
+}
+
+func TestHighLowBets(t *testing.T) {
+	tests := []struct {
+		bet    string
+		number int
+		want   bool
+	}{
+		{"low", 1, true},
+		{"low", 18, true},
+		{"low", 19, false},
+		{"high", 18, false},
+		{"high", 19, true},
+		{"high", 36, true},
+		{"low", 0, false},
+		{"high", 0, false},
+		{"low", DoubleZero, false},
+		{"high", DoubleZero, false},
+	}
+	for _, tt := range tests {
+		// Even money
+		want := -10.0
+		if tt.want {
+			want = 10
+		}
+		if got := playOn(t, "bankroll: 100\nbet: "+tt.bet+", 0, 10\n", tt.number); got != want {
+			t.Errorf("%s on %s: net %v, want %v", tt.bet, PocketLabel(tt.number), got, want)
+		}
+	}
+}
+
+func TestRedAndBlack(t *testing.T) {
+	red := map[int]bool{1: true, 3: true, 5: true, 7: true, 9: true, 12: true, 14: true, 16: true, 18: true,
+		19: true, 21: true, 23: true, 25: true, 27: true, 30: true, 32: true, 34: true, 36: true}
+	for n := 1; n <= 36; n++ {
+		if IsRed(n) != red[n] || IsBlack(n) == red[n] {
+			t.Errorf("%d: IsRed = %v, IsBlack = %v", n, IsRed(n), IsBlack(n))
+		}
+	}
+	for _, n := range []int{0, DoubleZero, 37, -5} {
+		if IsRed(n) || IsBlack(n) {
+			t.Errorf("%d is coloured", n)
+		}
+	}
+}
+
+func TestPayoutsAndProbabilities(t *testing.T) {
+	tests := []struct {
+		bet      string
+		multiple float64
+		pockets  int
+	}{
+		{"number", 36, 1},
+		{"split", 18, 2},
+		{"street", 12, 3},
+		{"trio", 12, 3},
+		{"corner", 9, 4},
+		{"basket", 7, 5},
+		{"line", 6, 6},
+		{"dozen", 3, 12},
+		{"column", 3, 12},
+		{"red", 2, 18},
+		{"black", 2, 18},
+		{"even", 2, 18},
+		{"odd", 2, 18},
+		{"low", 2, 18},
+		{"high", 2, 18},
+	}
+	for _, tt := range tests {
+		if got := PayoutMultiple(tt.bet); got != tt.multiple {
+			t.Errorf("PayoutMultiple(%s) = %v, want %v", tt.bet, got, tt.multiple)
+		}
+		if got := WinProbability(tt.bet, American); got != float64(tt.pockets)/38 {
+			t.Errorf("WinProbability(%s, american) = %v, want %d/38", tt.bet, got, tt.pockets)
+		}
+	}
+	if got := WinProbability("red", European); got != 18.0/37 {
+		t.Errorf("WinProbability(red, european) = %v, want 18/37", got)
+	}
+	if got := PayoutMultiple("nonsense"); got != 0 {
+		t.Errorf("PayoutMultiple(nonsense) = %v, want 0", got)
+	}
+}
+
+func TestPayoutOverrides(t *testing.T) {
+	standard := mustParse(t, "bankroll: 100000\nbet: number, 17, 10\n")
+	stingy := mustParse(t, "bankroll: 100000\npayout_number: 30\nbet: number, 17, 10\n")
+	generous := mustParse(t, "bankroll: 100000\npayout_number: 40\nbet: number, 17, 10\n")
+	if got := stingy.PayoutMultiple("number"); got != 31 {
+		t.Errorf("payout_number: 30 returns %v times the stake, want 31", got)
+	}
+	if got := stingy.PayoutMultiple("red"); got != 2 {
+		t.Errorf("red returns %v times the stake under a number override, want 2", got)
+	}
+	if got := generous.ExpectedValuePerRound(); got <= 0 {
+		t.Errorf("paying 40 to 1 on a number has expected value %v, want a player edge", got)
+	}
+	if !(stingy.ExpectedValuePerRound() < standard.ExpectedValuePerRound()) {
+		t.Errorf("expected value %v under 30 to 1 isn't below %v", stingy.ExpectedValuePerRound(), standard.ExpectedValuePerRound())
+	}
+
+	spins := []int{17, 2, 17}
+	for _, tt := range []struct {
+		strategy *Strategy
+		want     float64
+	}{{standard, 100000 + 350 - 10 + 350}, {stingy, 100000 + 300 - 10 + 300}} {
+		result, err := SimulateWithSpins(tt.strategy, spins)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.FinalBankroll != tt.want {
+			t.Errorf("final bankroll = %v, want %v", result.FinalBankroll, tt.want)
+		}
+	}
+	stingyRun := SimulateSeeded(stingy, 100_000, 70)
+	standardRun := SimulateSeeded(standard, 100_000, 70)
+	if stingyRun.FinalBankroll >= standardRun.FinalBankroll {
+		t.Errorf("same spins ended at %v under 30 to 1, not below %v", stingyRun.FinalBankroll, standardRun.FinalBankroll)
+	}
+
+	for _, text := range []string{"payout_number: 0\n", "payout_number: -5\n", "payout_purple: 3\n"} {
+		if _, err := ParseStrategy("bankroll: 100\nbet: red, 0, 10\n" + text); err == nil {
+			t.Errorf("%q was accepted", text)
+		}
+	}
+}
+
+func TestKellyFraction(t *testing.T) {
+	for _, betType := range []string{"red", "black", "odd", "even", "low", "high", "dozen", "column", "number", "split", "street", "corner", "line"} {
+		for _, wheel := range []WheelType{American, European} {
+			if f := KellyFraction(betType, wheel, 0); f >= 0 {
+				t.Errorf("%s on the %s wheel: Kelly fraction %v, want negative", betType, wheel, f)
+			}
+		}
+	}
+	if f, want := KellyFraction("red", American, 0), -2.0/38; math.Abs(f-want) > 1e-12 {
+		t.Errorf("red on the american wheel: Kelly fraction %v, want %v", f, want)
+	}
+
+	// A number paying 40 to 1 has an edge over the house
+	if f, want := KellyFraction("number", American, 40), (40.0/38-37.0/38)/40; f <= 0 || math.Abs(f-want) > 1e-12 {
+		t.Errorf("number paying 40 to 1: Kelly fraction %v, want %v", f, want)
+	}
+	// Paying fair odds leaves nothing to stake
+	if f := KellyFraction("number", American, 37); math.Abs(f) > 1e-12 {
+		t.Errorf("number paying 37 to 1: Kelly fraction %v, want 0", f)
+	}
+	if f := KellyFraction("nope", American, 0); f != 0 {
+		t.Errorf("unknown bet type: Kelly fraction %v, want 0", f)
+	}
+}
@@ -0,0 +1,208 @@
+package roulette
+
+// Result is the outcome of a settled bet
+type Result int
+
+const (
+	// NoResult means the bet hasn't been settled yet
+	NoResult Result = iota
+	// Win means the bet paid out
+	Win
+	// Loss means the stake was lost
+	Loss
+)
+
+// Progression decides the stake of a bet from its base amount and the
+// result of the last time it was settled
+type Progression interface {
+	NextBet(base float64, lastResult Result) float64
+}
+
+// isProgression reports whether name is a known progression
+func isProgression(name string) bool {
+	switch name {
+	case "flat", "martingale", "fibonacci", "dalembert", "labouchere", "paroli":
+		return true
+	}
+	return false
+}
+
+// newProgression creates a fresh progression of the named kind for a single
+// bet, set up by the strategy's progression settings
+func newProgression(strategy *Strategy, name string) Progression {
+	switch name {
+	case "martingale":
+		return &Martingale{maxSteps: strategy.MartingaleMaxSteps}
+	case "fibonacci":
+		return &Fibonacci{}
+	case "dalembert":
+		return &DAlembert{unit: strategy.Unit}
+	case "labouchere":
+		start := strategy.LabouchereLine
+		if len(start) == 0 {
+			start = defaultLabouchereLine
+		}
+		return &Labouchere{start: start}
+	case "paroli":
+		steps := strategy.ParoliSteps
+		if steps == 0 {
+			steps = defaultParoliSteps
+		}
+		return &Paroli{steps: steps}
+	}
+	return FlatProgression{}
+}
+
+// FlatProgression always stakes the base amount
+type FlatProgression struct{}
+
+// NextBet returns the base amount
+func (FlatProgression) NextBet(base float64, lastResult Result) float64 {
+	return base
+}
+
+// Martingale doubles the stake after every loss and returns to the base
+// amount after a win. With maxSteps set it also gives up and returns to the
+// base amount after that many losses in a row.
+type Martingale struct {
+	maxSteps int
+	losses   int
+	stake    float64
+}
+
+// NextBet returns the stake for the next round
+func (m *Martingale) NextBet(base float64, lastResult Result) float64 {
+	if lastResult == Loss && m.stake > 0 {
+		m.losses++
+		m.stake *= 2
+	} else {
+		m.losses = 0
+		m.stake = base
+	}
+	if m.maxSteps > 0 && m.losses >= m.maxSteps {
+		m.losses = 0
+		m.stake = base
+	}
+	return m.stake
+}
+
+// Fibonacci stakes the base amount times the current term of the Fibonacci
+// sequence 1, 1, 2, 3, 5, ..., moving forward one term after a loss and back
+// two terms after a win
+type Fibonacci struct {
+	index int
+}
+
+// NextBet returns the stake for the next round
+func (f *Fibonacci) NextBet(base float64, lastResult Result) float64 {
+	switch lastResult {
+	case Loss:
+		f.index++
+	case Win:
+		f.index -= 2
+		if f.index < 0 {
+			f.index = 0
+		}
+	}
+	return base * float64(fibonacci(f.index))
+}
+
+// fibonacci returns the nth term of the sequence 1, 1, 2, 3, 5, ...
+func fibonacci(n int) int {
+	a, b := 1, 1
+	for i := 0; i < n; i++ {
+		a, b = b, a+b
+	}
+	return a
+}
+
+// DAlembert raises the stake by one unit after a loss and lowers it by one
+// unit after a win, never going below a single unit
+type DAlembert struct {
+	unit  float64
+	stake float64
+}
+
+// NextBet returns the stake for the next round
+func (d *DAlembert) NextBet(base float64, lastResult Result) float64 {
+	unit := d.unit
+	if unit <= 0 {
+		unit = base
+	}
+	switch lastResult {
+	case NoResult:
+		d.stake = base
+	case Loss:
+		d.stake += unit
+	case Win:
+		d.stake -= unit
+	}
+	if d.stake < unit {
+		d.stake = unit
+	}
+	return d.stake
+}
+
+// defaultLabouchereLine is used when a strategy doesn't set labouchere_line
+var defaultLabouchereLine = []float64{1, 2, 3, 4}
+
+// Labouchere keeps a line of numbers and stakes the sum of the first and last.
+// A win crosses both off, a loss adds the amount lost to the end, and once
+// the line is empty it starts over.
+type Labouchere struct {
+	start []float64
+	line  []float64
+}
+
+// NextBet returns the stake for the next round
+func (l *Labouchere) NextBet(base float64, lastResult Result) float64 {
+	switch lastResult {
+	case Win:
+		if len(l.line) <= 2 {
+			l.line = nil
+		} else {
+			l.line = l.line[1 : len(l.line)-1]
+		}
+	case Loss:
+		l.line = append(l.line, l.units())
+	}
+	if len(l.line) == 0 {
+		l.line = append([]float64(nil), l.start...)
+	}
+	return base * l.units()
+}
+
+// units returns the current stake in units of the base amount
+func (l *Labouchere) units() float64 {
+	if len(l.line) == 1 {
+		return l.line[0]
+	}
+	return l.line[0] + l.line[len(l.line)-1]
+}
+
+// defaultParoliSteps is used when a strategy doesn't set paroli_steps
+const defaultParoliSteps = 3
+
+// Paroli doubles the stake after each win and returns to the base amount
+// after a loss or once the configured number of wins in a row is reached
+type Paroli struct {
+	steps int
+	wins  int
+	stake float64
+}
+
+// NextBet returns the stake for the next round
+func (p *Paroli) NextBet(base float64, lastResult Result) float64 {
+	if lastResult == Win {
+		p.wins++
+	} else {
+		p.wins = 0
+	}
+	if p.wins == 0 || p.wins >= p.steps {
+		p.wins = 0
+		p.stake = base
+	} else {
+		p.stake *= 2
+	}
+	return p.stake
+}
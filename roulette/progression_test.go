@@ -0,0 +1,211 @@
+package roulette
+
+import (
+	"reflect"
+	"testing"
+)
+
+// progressionStakes feeds results to a progression and returns the stake it
+// picks before each one, plus the stake after the last
+func progressionStakes(p Progression, base float64, results ...Result) []float64 {
+	stakes := []float64{p.NextBet(base, NoResult)}
+	for _, result := range results {
+		stakes = append(stakes, p.NextBet(base, result))
+	}
+	return stakes
+}
+
+// replayStakes replays spins against the strategy and returns the stake of
+// its first bet on every spin
+func replayStakes(t *testing.T, text string, spins []int) (*SimulationResult, []float64) {
+	t.Helper()
+	strategy, err := ParseStrategy(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ReplayWithOptions(strategy, spins, SimulationOptions{RecordSpins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stakes []float64
+	for _, record := range result.SpinLog {
+		stakes = append(stakes, record.Stakes[0])
+	}
+	return result, stakes
+}
+
+func TestMartingale(t *testing.T) {
+	got := progressionStakes(&Martingale{}, 10, Loss, Loss, Win, Loss, Win)
+	want := []float64{10, 20, 40, 10, 20, 10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stakes = %v, want %v", got, want)
+	}
+
+	// 2 is black, 1 is red: the win on 40 recovers both losses and the
+	// stake drops back to 10
+	if got := replay(t, "bankroll: 1000\nprogression: martingale\nbet: red, 0, 10\n", 2, 2, 1, 2).FinalBankroll; got != 1000 {
+		t.Errorf("final bankroll = %v, want 1000", got)
+	}
+}
+
+func TestMartingaleStopsWhenBankrollIsExhausted(t *testing.T) {
+	// Losing 10, 20 and 40 leaves nothing to cover the next stake of 80,
+	// so the remaining spins are skipped rather than driving it negative
+	result := replay(t, "bankroll: 70\nprogression: martingale\nbet: red, 0, 10\n", 2, 2, 2, 2, 2)
+	if result.BetsSkipped != 2 || result.FinalBankroll != 0 {
+		t.Errorf("skipped %d bets leaving %v, want 2 skipped and nothing left", result.BetsSkipped, result.FinalBankroll)
+	}
+	if !result.WentBust || result.BustSpin != 3 {
+		t.Errorf("WentBust = %v after spin %d, want bust after spin 3", result.WentBust, result.BustSpin)
+	}
+	if got := replay(t, "bankroll: 100\nprogression: martingale\nbet: red, 0, 10\n", 2, 2, 2, 2).FinalBankroll; got != 30 {
+		t.Errorf("final bankroll = %v, want 30 once the 80 stake can't be covered", got)
+	}
+}
+
+func TestFibonacci(t *testing.T) {
+	f := &Fibonacci{}
+	got := progressionStakes(f, 10, Loss, Loss, Loss, Loss, Win, Win, Win, Win)
+	want := []float64{10, 10, 20, 30, 50, 20, 10, 10, 10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stakes = %v, want %v", got, want)
+	}
+	if f.index != 0 {
+		t.Errorf("index = %d after repeated wins, want it clamped at 0", f.index)
+	}
+	for _, stake := range got {
+		if stake < 10 {
+			t.Errorf("stake %v went below the base amount", stake)
+		}
+	}
+}
+
+func TestDAlembert(t *testing.T) {
+	got := progressionStakes(&DAlembert{}, 10, Loss, Loss, Win, Win, Win, Loss)
+	want := []float64{10, 20, 30, 20, 10, 10, 20}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stakes = %v, want %v", got, want)
+	}
+
+	got = progressionStakes(&DAlembert{unit: 5}, 10, Loss, Win, Win, Win, Loss)
+	want = []float64{10, 15, 10, 5, 5, 10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stakes with a 5 unit = %v, want %v", got, want)
+	}
+
+	// Stakes of 10, 15 and 20 on two losses and a win
+	if got := replay(t, "bankroll: 1000\nprogression: dalembert\nunit: 5\nbet: red, 0, 10\n", 2, 2, 1).FinalBankroll; got != 995 {
+		t.Errorf("final bankroll = %v, want 995", got)
+	}
+}
+
+func TestMartingaleCappedAtTableMax(t *testing.T) {
+	const text = "bankroll: 1000\nprogression: martingale\ntable_max: 40\nbet: red, 0, 10\n"
+	// Stakes of 10, 20, 40, 40, 40, then 40 on the win and back to 10
+	result := replay(t, text, 2, 2, 2, 2, 2, 1, 2)
+	if result.FinalBankroll != 880 {
+		t.Errorf("capped final bankroll = %v, want 880", result.FinalBankroll)
+	}
+	if result.TableLimitHit != 3 {
+		t.Errorf("table limit hit %d times, want 3", result.TableLimitHit)
+	}
+
+	// Stakes of 10, 20, 40, then the progression starts over at 10 and 20
+	if got := replay(t, text+"limit_policy: abandon\n", 2, 2, 2, 2, 2).FinalBankroll; got != 900 {
+		t.Errorf("abandoned final bankroll = %v, want 900", got)
+	}
+}
+
+func TestTableMaxChangesBustRate(t *testing.T) {
+	const text = "bankroll: 300\nprogression: martingale\nbet: red, 0, 10\n"
+	uncapped, err := ParseStrategy(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	capped, err := ParseStrategy(text + "table_max: 40\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	free := RunMonteCarloSeeded(uncapped, 500, 500, 8)
+	limited := RunMonteCarloSeeded(capped, 500, 500, 8)
+	if limited.BustPercent >= free.BustPercent {
+		t.Errorf("bust rate %v%% capped at 40, want it below the uncapped %v%%", limited.BustPercent, free.BustPercent)
+	}
+}
+
+func TestTableLimitValidation(t *testing.T) {
+	for _, text := range []string{"table_min: 0\n", "table_max: -5\n", "table_min: 50\ntable_max: 20\n", "limit_policy: maybe\n"} {
+		if _, err := ParseStrategy("bankroll: 100\nbet: red, 0, 10\n" + text); err == nil {
+			t.Errorf("%q was accepted", text)
+		}
+	}
+}
+
+func TestLabouchere(t *testing.T) {
+	l := &Labouchere{start: []float64{1, 2, 3, 4}}
+	steps := []struct {
+		result Result
+		stake  float64
+		line   []float64
+	}{
+		{NoResult, 50, []float64{1, 2, 3, 4}},
+		{Win, 50, []float64{2, 3}},
+		{Loss, 70, []float64{2, 3, 5}},
+		{Win, 30, []float64{3}},
+		{Win, 50, []float64{1, 2, 3, 4}},
+	}
+	for i, step := range steps {
+		if got := l.NextBet(10, step.result); got != step.stake {
+			t.Errorf("step %d: stake = %v, want %v", i, got, step.stake)
+		}
+		if !reflect.DeepEqual(l.line, step.line) {
+			t.Errorf("step %d: line = %v, want %v", i, l.line, step.line)
+		}
+	}
+
+	_, stakes := replayStakes(t, "bankroll: 1000\nprogression: labouchere\nlabouchere_line: 1 1\nbet: red, 0, 10\n", []int{2, 1, 1})
+	if want := []float64{20, 30, 10}; !reflect.DeepEqual(stakes, want) {
+		t.Errorf("replayed stakes = %v, want %v", stakes, want)
+	}
+}
+
+func TestParoli(t *testing.T) {
+	got := progressionStakes(&Paroli{steps: 3}, 10, Win, Win, Win, Win, Loss, Win, Loss, Loss)
+	want := []float64{10, 20, 40, 10, 20, 10, 20, 10, 10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stakes = %v, want %v", got, want)
+	}
+
+	_, stakes := replayStakes(t, "bankroll: 1000\nprogression: paroli\nparoli_steps: 2\nbet: red, 0, 10\n", []int{1, 1, 1, 2})
+	if want := []float64{10, 20, 10, 20}; !reflect.DeepEqual(stakes, want) {
+		t.Errorf("replayed stakes = %v, want %v", stakes, want)
+	}
+}
+
+func TestMartingaleMaxSteps(t *testing.T) {
+	got := progressionStakes(&Martingale{maxSteps: 3}, 10, Loss, Loss, Loss, Loss, Loss, Win)
+	want := []float64{10, 20, 40, 10, 20, 40, 10}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stakes = %v, want %v", got, want)
+	}
+
+	_, stakes := replayStakes(t, "bankroll: 1000\nprogression: martingale\nmartingale_max_steps: 2\nbet: red, 0, 10\n", []int{2, 2, 2, 2})
+	if want := []float64{10, 20, 10, 20}; !reflect.DeepEqual(stakes, want) {
+		t.Errorf("replayed stakes = %v, want %v", stakes, want)
+	}
+
+	const text = "bankroll: 300\nprogression: martingale\nbet: red, 0, 10\n"
+	uncapped, err := ParseStrategy(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	capped, err := ParseStrategy(text + "martingale_max_steps: 3\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	free := RunMonteCarloSeeded(uncapped, 500, 500, 8)
+	limited := RunMonteCarloSeeded(capped, 500, 500, 8)
+	if limited.BustPercent >= free.BustPercent {
+		t.Errorf("bust rate %v%% with 3 steps, want it below the uncapped %v%%", limited.BustPercent, free.BustPercent)
+	}
+}
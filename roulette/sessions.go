@@ -0,0 +1,53 @@
+package roulette
+
+// SessionResult sums up a player's repeated visits to the casino, each
+// starting over with the strategy's initial bankroll
+type SessionResult struct {
+	Sessions     int       `json:"sessions"`
+	Seed         int64     `json:"seed"`          // Seed of the first session; session i is seeded with Seed+i
+	Profits      []float64 `json:"profits"`       // Profit or loss of every session, in the order played
+	Won          int       `json:"won"`           // Sessions that ended above the initial bankroll
+	Lost         int       `json:"lost"`          // Sessions that ended below it, busted ones included
+	Busted       int       `json:"busted"`        // Sessions that ended unable to cover any bet
+	TotalProfit  float64   `json:"total_profit"`  // Net result of every visit put together
+	WorstRunning float64   `json:"worst_running"` // Lowest the running total fell to along the way
+	TotalWagered float64   `json:"total_wagered"`
+}
+
+// SimulateSessions plays numSessions sessions of up to spinsPerSession spins,
+// resetting the bankroll at the start of each one. Unlike RunMonteCarlo, the
+// sessions are kept in order as a series of visits by the same player, so
+// the running total shows how the losses pile up over time.
+func SimulateSessions(strategy *Strategy, spinsPerSession, numSessions int) *SessionResult {
+	return SimulateSessionsSeeded(strategy, spinsPerSession, numSessions, newSeed())
+}
+
+// SimulateSessionsSeeded is like SimulateSessions but seeds session i with
+// seed+i, so the series of visits is reproducible
+func SimulateSessionsSeeded(strategy *Strategy, spinsPerSession, numSessions int, seed int64) *SessionResult {
+	sr := &SessionResult{Seed: seed}
+	var total, wagered int64
+	for i := 0; i < numSessions; i++ {
+		result := SimulateSeeded(strategy, spinsPerSession, seed+int64(i))
+		profit := ToCents(result.FinalBankroll) - ToCents(strategy.InitialBankroll)
+		sr.Sessions++
+		sr.Profits = append(sr.Profits, FromCents(profit))
+		switch {
+		case profit > 0:
+			sr.Won++
+		case profit < 0:
+			sr.Lost++
+		}
+		if result.WentBust {
+			sr.Busted++
+		}
+		total += profit
+		wagered += ToCents(result.TotalWagered)
+		if FromCents(total) < sr.WorstRunning {
+			sr.WorstRunning = FromCents(total)
+		}
+	}
+	sr.TotalProfit = FromCents(total)
+	sr.TotalWagered = FromCents(wagered)
+	return sr
+}
@@ -0,0 +1,52 @@
+package roulette
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimulateSessions(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 50\nbet: red, 0, 10\n")
+	sr := SimulateSessionsSeeded(strategy, 30, 40, 76)
+	if sr.Sessions != 40 || len(sr.Profits) != 40 {
+		t.Fatalf("played %d sessions with %d profits, want 40", sr.Sessions, len(sr.Profits))
+	}
+
+	// Every session starts from the initial bankroll, busted ones before it
+	// included, so each is the same as a lone run from its seed
+	sum, running, worst := 0.0, 0.0, 0.0
+	won, lost, busted := 0, 0, 0
+	for i, profit := range sr.Profits {
+		alone := SimulateSeeded(strategy, 30, 76+int64(i))
+		if want := alone.FinalBankroll - 50; profit != want {
+			t.Errorf("session %d made %v, want %v from a fresh bankroll", i, profit, want)
+		}
+		if profit < -50 {
+			t.Errorf("session %d lost %v, more than the bankroll brought", i, -profit)
+		}
+		sum += profit
+		running += profit
+		worst = math.Min(worst, running)
+		switch {
+		case profit > 0:
+			won++
+		case profit < 0:
+			lost++
+		}
+		if alone.WentBust {
+			busted++
+		}
+	}
+	if math.Abs(sr.TotalProfit-sum) > 1e-9 {
+		t.Errorf("total profit = %v, want the sum of sessions %v", sr.TotalProfit, sum)
+	}
+	if sr.Won != won || sr.Lost != lost || sr.Busted != busted {
+		t.Errorf("won/lost/busted = %d/%d/%d, want %d/%d/%d", sr.Won, sr.Lost, sr.Busted, won, lost, busted)
+	}
+	if busted == 0 {
+		t.Error("no session went bust, so starting fresh wasn't tested")
+	}
+	if math.Abs(sr.WorstRunning-worst) > 1e-9 {
+		t.Errorf("worst running total = %v, want %v", sr.WorstRunning, worst)
+	}
+}
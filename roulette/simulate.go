@@ -0,0 +1,814 @@
+package roulette
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SimulationResult summarizes a single simulated session
+type SimulationResult struct {
+	FinalBankroll     float64           `json:"final_bankroll"`
+	PeakBankroll      float64           `json:"peak_bankroll"`
+	MinBankroll       float64           `json:"min_bankroll"` // Lowest bankroll seen, the bottom of the worst drawdown
+	SpinsPlayed       int               `json:"spins_played"`
+	SpinsSpectated    int               `json:"spins_spectated"` // Spins watched without betting, under bet_every or waiting for the trigger, counted in SpinsPlayed
+	Seed              int64             `json:"seed,omitempty"`  // Seed the wheel was spun with, zero for replayed spins
+	BetsWon           int               `json:"bets_won"`
+	BetsLost          int               `json:"bets_lost"`
+	LongestWinStreak  int               `json:"longest_win_streak"`  // Most spins in a row with a net gain
+	LongestLossStreak int               `json:"longest_loss_streak"` // Most spins in a row with a net loss
+	TotalWagered      float64           `json:"total_wagered"`
+	TotalReturned     float64           `json:"total_returned"`          // Everything paid back on settled bets, stakes included
+	RTP               float64           `json:"rtp"`                     // Return to player, TotalReturned as a percentage of TotalWagered
+	HouseProfit       float64           `json:"house_profit"`            // What the casino won off the player, the player's loss turned around
+	WentBust          bool              `json:"went_bust"`               // The final bankroll can't cover any of the bets
+	BustSpin          int               `json:"bust_spin,omitempty"`     // Spin after which no bet could be afforded, if the run went bust
+	StoppedOnBust     bool              `json:"stopped_on_bust"`         // The stop bust policy ended the run early
+	Outcome           Outcome           `json:"outcome"`                 // Why the run ended
+	PhaseStarts       []int             `json:"phase_starts,omitempty"`  // Spins after which each phase past the first began
+	TableLimitHit     int               `json:"table_limit_hit"`         // Number of stakes pushed outside the table limits
+	BetsSkipped       int               `json:"bets_skipped"`            // Bets left off the table because the bankroll couldn't cover them
+	TriggersFired     int               `json:"triggers_fired"`          // Betting episodes the strategy's trigger started
+	SpinLog           []SpinRecord      `json:"spin_log,omitempty"`      // Every spin in order, when SimulationOptions.RecordSpins is set without a SpinSink
+	PocketCounts      map[int]int       `json:"pocket_counts,omitempty"` // Times each pocket came up, when SimulationOptions.CountPockets is set
+	PerBet            map[int]*BetStats `json:"per_bet"`                 // Keyed by the bet's index in the strategy
+}
+
+// Outcome is the reason a simulated run ended. When several reasons apply
+// after the same spin, the first of these wins: the stop-loss, the
+// take-profit, the loss-streak limit, going bust, and only then running out
+// of spins, whether at the requested count or at SimulationOptions.MaxSpins.
+// So a run that reaches its take-profit on its very last spin ends
+// ReachedTakeProfit rather than CompletedAllSpins.
+type Outcome int
+
+const (
+	// CompletedAllSpins means every requested spin was played
+	CompletedAllSpins Outcome = iota
+	// ReachedTakeProfit means the bankroll rose to the take-profit
+	ReachedTakeProfit
+	// HitStopLoss means the bankroll fell to the stop-loss
+	HitStopLoss
+	// HitLossStreakLimit means max_loss_streak spins in a row were lost
+	HitLossStreakLimit
+	// WentBust means no bet could be afforded under the stop bust policy
+	WentBust
+	// HitSpinLimit means the run was cut short by SimulationOptions.MaxSpins
+	HitSpinLimit
+	// Interrupted means the context was done before the run finished
+	Interrupted
+)
+
+// String returns the name of the outcome as it appears in JSON
+func (o Outcome) String() string {
+	switch o {
+	case CompletedAllSpins:
+		return "completed"
+	case ReachedTakeProfit:
+		return "take_profit"
+	case HitStopLoss:
+		return "stop_loss"
+	case HitLossStreakLimit:
+		return "loss_streak"
+	case WentBust:
+		return "bust"
+	case HitSpinLimit:
+		return "max_spins"
+	case Interrupted:
+		return "interrupted"
+	}
+	return fmt.Sprintf("Outcome(%d)", int(o))
+}
+
+// MarshalText writes the outcome by name, so JSON results stay readable
+func (o Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText reads an outcome written by MarshalText, so saved results
+// can be loaded back
+func (o *Outcome) UnmarshalText(text []byte) error {
+	for candidate := CompletedAllSpins; candidate <= Interrupted; candidate++ {
+		if candidate.String() == string(text) {
+			*o = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown outcome: %s", text)
+}
+
+// BetStats tracks how a single bet of a strategy performed
+type BetStats struct {
+	TimesPlaced  int     `json:"times_placed"`
+	TimesWon     int     `json:"times_won"`
+	TimesSkipped int     `json:"times_skipped"` // Spins the bankroll couldn't cover the stake
+	TotalWagered float64 `json:"total_wagered"`
+	NetProfit    float64 `json:"net_profit"`
+}
+
+// SpinRecord describes what happened on a single spin
+type SpinRecord struct {
+	Spin           int       `json:"spin"` // 1-based index of the spin
+	WinningNumber  int       `json:"winning_number"`
+	WinningNumbers []int     `json:"winning_numbers,omitempty"` // Every wheel's number, when the strategy plays more than one
+	Stakes         []float64 `json:"stakes"`                    // Stake placed on each bet across all wheels, zero when it was skipped
+	Skipped        []int     `json:"skipped,omitempty"`         // Indexes of the bets the bankroll couldn't cover, in ascending order
+	Target         *int      `json:"target,omitempty"`          // Pocket adaptive number bets were moved to, if any
+	Spectated      bool      `json:"spectated,omitempty"`       // No bets were placed on the spin, under bet_every or waiting for the trigger
+	NetChange      float64   `json:"net_change"`
+	Bankroll       float64   `json:"bankroll"` // Bankroll after the spin was settled
+}
+
+// SimulationOptions configures SimulateWithOptions
+type SimulationOptions struct {
+	NumGames     int
+	Seed         int64 // Seed for the wheel, zero to pick one from the clock
+	RecordSpins  bool  // Fill in SimulationResult.SpinLog
+	CountPockets bool  // Fill in SimulationResult.PocketCounts
+	MaxSpins     int   // Most spins a run may play, zero for DefaultMaxSpins
+
+	// SpinSink, if set, receives every spin record as it is played, in
+	// SpinFormat: "csv" (the default), "json" lines or "binary", the compact
+	// layout read by ReadSpinLogBinary. Streamed records are never kept in
+	// SimulationResult.SpinLog, so RecordSpins has no effect, and a run of
+	// any length fits in memory.
+	SpinSink   io.Writer
+	SpinFormat string
+
+	// ProgressFunc, if set, is called with the bankroll after every
+	// ProgressEvery spins
+	ProgressEvery int
+	ProgressFunc  func(spinsDone int, bankroll float64)
+}
+
+// DefaultMaxSpins caps the length of a run when SimulationOptions.MaxSpins
+// isn't set, so a mistyped game count can't keep a simulation going forever
+const DefaultMaxSpins = 1_000_000_000
+
+// SimulateRoulette simulates roulette games using the given strategy. Fewer
+// than numGames spins are played when a stop-loss or take-profit ends the
+// session early.
+func SimulateRoulette(strategy *Strategy, numGames int) *SimulationResult {
+	return SimulateSeeded(strategy, numGames, newSeed())
+}
+
+// SimulateSeeded is like SimulateRoulette but spins a wheel seeded with seed,
+// so the same seed always reproduces the same session
+func SimulateSeeded(strategy *Strategy, numGames int, seed int64) *SimulationResult {
+	result := simulate(strategy, SimulationOptions{NumGames: numGames}, newSpinner(strategy, seed))
+	result.Seed = seed
+	return result
+}
+
+// SimulateWithOptions simulates roulette games using the given strategy with
+// optional extras such as a spin-by-spin log
+func SimulateWithOptions(strategy *Strategy, opts SimulationOptions) *SimulationResult {
+	result, _ := SimulateWithOptionsContext(context.Background(), strategy, opts)
+	return result
+}
+
+// SimulateContext is like SimulateRoulette but stops early once ctx is done,
+// returning the result of the spins played so far along with ctx's error
+func SimulateContext(ctx context.Context, strategy *Strategy, numGames int) (*SimulationResult, error) {
+	return SimulateWithOptionsContext(ctx, strategy, SimulationOptions{NumGames: numGames})
+}
+
+// SimulateWithOptionsContext is like SimulateWithOptions but stops early
+// once ctx is done, in the same way as SimulateContext
+func SimulateWithOptionsContext(ctx context.Context, strategy *Strategy, opts SimulationOptions) (*SimulationResult, error) {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = newSeed()
+	}
+	result, err := simulateContext(ctx, strategy, opts, newSpinner(strategy, seed))
+	result.Seed = seed
+	return result, err
+}
+
+// newSpinner returns a spin function for the strategy's wheels. With more
+// than one wheel the calls take turns between them, and every wheel past
+// the first gets its own seed drawn from seed, so each has its own stream.
+func newSpinner(strategy *Strategy, seed int64) func() int {
+	wheels := make([]*RouletteWheel, strategy.wheelCount())
+	wheels[0] = newSeededWheel(strategy.Wheel, seed)
+	if len(wheels) == 1 {
+		return wheels[0].Spin
+	}
+	seeder := rand.New(rand.NewSource(^seed))
+	for k := 1; k < len(wheels); k++ {
+		wheels[k] = newSeededWheel(strategy.Wheel, seeder.Int63())
+	}
+	next := 0
+	return func() int {
+		n := wheels[next].Spin()
+		next = (next + 1) % len(wheels)
+		return n
+	}
+}
+
+// SimulateWithSpins plays the strategy against a known sequence of winning
+// numbers, such as spins recorded at a real table, instead of a random wheel.
+// A strategy with several wheels takes one number from the sequence for each
+// of its wheels every round.
+func SimulateWithSpins(strategy *Strategy, spins []int) (*SimulationResult, error) {
+	return ReplayWithOptions(strategy, spins, SimulationOptions{})
+}
+
+// ReplayWithOptions is like SimulateWithSpins but takes the same options as
+// SimulateWithOptions. Every spin is played, so NumGames and Seed are ignored.
+func ReplayWithOptions(strategy *Strategy, spins []int, opts SimulationOptions) (*SimulationResult, error) {
+	pockets := NewWheel(strategy.Wheel).Numbers
+	for i, n := range spins {
+		if !contains(pockets, n) {
+			return nil, fmt.Errorf("spin %d: %s is not a pocket on the %s wheel", i+1, PocketLabel(n), strategy.Wheel)
+		}
+	}
+	wheels := strategy.wheelCount()
+	if len(spins)%wheels != 0 {
+		return nil, fmt.Errorf("%d spins don't divide evenly between %d wheels", len(spins), wheels)
+	}
+	next := 0
+	spin := func() int {
+		next++
+		return spins[next-1]
+	}
+	opts.NumGames = len(spins) / wheels
+	return simulateContext(context.Background(), strategy, opts, spin)
+}
+
+// simulate plays the strategy, calling spin for each winning number, once
+// per wheel each round
+func simulate(strategy *Strategy, opts SimulationOptions, spin func() int) *SimulationResult {
+	result, _ := simulateContext(context.Background(), strategy, opts, spin)
+	return result
+}
+
+// cancelCheckInterval is how many spins are played between checks of the
+// context, so cancellation stays responsive without slowing every spin
+const cancelCheckInterval = 4096
+
+// simulateContext is like simulate but gives up early once ctx is done
+func simulateContext(ctx context.Context, strategy *Strategy, opts SimulationOptions, spin func() int) (*SimulationResult, error) {
+	sess := newSession(strategy, opts)
+	if opts.SpinSink != nil {
+		sink, err := newSpinSink(opts.SpinSink, opts.SpinFormat, len(strategy.Bets), strategy.wheelCount())
+		if err != nil {
+			return sess.close(), err
+		}
+		sess.sink = sink
+	}
+	progress := opts.ProgressFunc != nil && opts.ProgressEvery > 0
+	numGames, maxSpins := opts.NumGames, opts.MaxSpins
+	if maxSpins <= 0 {
+		maxSpins = DefaultMaxSpins
+	}
+	if numGames > maxSpins {
+		numGames = maxSpins
+	}
+	numbers := make([]int, strategy.wheelCount())
+	// finished is checked first so that a stop condition met on the last
+	// spin still decides the outcome, as documented on Outcome
+	for !sess.finished() && sess.result.SpinsPlayed < numGames {
+		if sess.result.SpinsPlayed%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				sess.result.Outcome = Interrupted
+				return sess.end(err)
+			}
+		}
+		for k := range numbers {
+			numbers[k] = spin()
+		}
+		sess.play(numbers)
+		if sess.sink != nil && sess.sink.err != nil {
+			return sess.end(nil)
+		}
+		if progress && sess.result.SpinsPlayed%opts.ProgressEvery == 0 {
+			opts.ProgressFunc(sess.result.SpinsPlayed, FromCents(sess.bankroll))
+		}
+	}
+	if sess.result.Outcome == CompletedAllSpins && numGames < opts.NumGames && sess.result.SpinsPlayed == numGames {
+		sess.result.Outcome = HitSpinLimit
+	}
+	return sess.end(nil)
+}
+
+// end closes the run and flushes the spin sink, returning err or else any
+// error the sink ran into
+func (sess *session) end(err error) (*SimulationResult, error) {
+	result := sess.close()
+	if sess.sink != nil {
+		if sinkErr := sess.sink.flush(); err == nil && sinkErr != nil {
+			err = fmt.Errorf("writing spins: %w", sinkErr)
+		}
+	}
+	return result, err
+}
+
+// session is the state of a single simulated run. Money is tracked in whole
+// cents so long sessions don't drift the way summed floats would.
+type session struct {
+	strategy *Strategy
+	opts     SimulationOptions
+	result   *SimulationResult
+
+	bankroll   int64
+	peak       int64
+	lowest     int64
+	wagered    int64
+	betWagered []int64
+	betNet     []int64
+	winStreak  int
+	lossStreak int
+
+	// Each bet runs its own progression, and only moves it on once the bet
+	// has actually been settled. pending holds the progression's latest
+	// answer: a dollar amount for fixed bets, or a multiple of the
+	// percentage for percentage bets.
+	progressions []Progression
+	pending      []float64
+
+	// imprisoned holds, for each wheel, the stake of each even-money bet
+	// locked up by a zero under the en prison rule, waiting for that wheel's
+	// next spin to decide it
+	imprisoned [][]int64
+
+	// Bets skipped for want of funds this round, when logging spins
+	skipped []int
+
+	// sink streams spin records in place of SpinLog, if set
+	sink *spinSink
+
+	// spectating is set for a spin the player sits out, under bet_every or
+	// while the trigger hasn't fired. triggerStreak counts the spins in a row
+	// that have met the trigger, and triggered is set for an episode of
+	// betting it has started.
+	spectating    bool
+	triggerStreak int
+	triggered     bool
+
+	// phase is the 1-based phase being played, zero for a strategy without
+	// phases, which began with phaseBankroll after phaseSpin spins
+	phase         int
+	phaseBankroll int64
+	phaseSpin     int
+
+	// Adaptive betting moves every number bet to target, the hot or cold
+	// pocket of the spins in window, once it has seen a spin
+	window    *spinWindow
+	pockets   []int
+	target    int
+	hasTarget bool
+}
+
+// newSession sets up a run of the strategy from its initial bankroll
+func newSession(strategy *Strategy, opts SimulationOptions) *session {
+	bankroll := ToCents(strategy.InitialBankroll)
+	sess := &session{
+		strategy:     strategy,
+		opts:         opts,
+		result:       &SimulationResult{PerBet: make(map[int]*BetStats, len(strategy.Bets))},
+		bankroll:     bankroll,
+		peak:         bankroll,
+		lowest:       bankroll,
+		betWagered:   make([]int64, len(strategy.Bets)),
+		betNet:       make([]int64, len(strategy.Bets)),
+		progressions: make([]Progression, len(strategy.Bets)),
+		pending:      make([]float64, len(strategy.Bets)),
+		imprisoned:   make([][]int64, strategy.wheelCount()),
+	}
+	if opts.CountPockets {
+		sess.result.PocketCounts = make(map[int]int)
+	}
+	if len(strategy.Phases) > 0 {
+		sess.phase, sess.phaseBankroll = 1, bankroll
+	}
+	if strategy.Adaptive != "" {
+		sess.window = newSpinWindow(strategy.adaptiveWindow())
+		sess.pockets = NewWheel(strategy.Wheel).Numbers
+	}
+	for k := range sess.imprisoned {
+		sess.imprisoned[k] = make([]int64, len(strategy.Bets))
+	}
+	for j := range strategy.Bets {
+		sess.result.PerBet[j] = &BetStats{}
+		sess.progressions[j] = newProgression(strategy, strategy.progressionFor(j))
+		sess.pending[j] = sess.progressions[j].NextBet(sess.base(j), NoResult)
+	}
+	return sess
+}
+
+// finished reports whether the run should end before the next spin, setting
+// the outcome to the first stop condition met in the order documented on
+// Outcome
+func (sess *session) finished() bool {
+	strategy := sess.strategy
+	if strategy.StopLoss != nil && sess.bankroll <= ToCents(*strategy.StopLoss) {
+		sess.result.Outcome = HitStopLoss
+		return true
+	}
+	if takeProfit, ok := strategy.takeProfit(); ok && sess.bankroll >= ToCents(takeProfit) {
+		sess.result.Outcome = ReachedTakeProfit
+		return true
+	}
+	if strategy.MaxLossStreak > 0 && sess.lossStreak >= strategy.MaxLossStreak {
+		sess.result.Outcome = HitLossStreakLimit
+		return true
+	}
+	if strategy.BustPolicy == "stop" && !sess.canAfford() {
+		sess.result.StoppedOnBust = true
+		sess.result.Outcome = WentBust
+		return true
+	}
+	return false
+}
+
+// play plays one round, placing every bet the bankroll covers on each wheel
+// and settling it against that wheel's winning number
+func (sess *session) play(winningNumbers []int) {
+	result := sess.result
+	result.SpinsPlayed++
+	bankrollBefore := sess.bankroll
+	trigger := sess.strategy.Trigger
+	sess.spectating = !sess.strategy.betsOn(result.SpinsPlayed) ||
+		trigger != nil && !sess.triggered
+	if sess.spectating {
+		result.SpinsSpectated++
+	}
+	logging := sess.opts.RecordSpins || sess.sink != nil
+	var placed []float64
+	if logging {
+		placed = make([]float64, len(sess.strategy.Bets))
+	}
+	if sess.window != nil {
+		sess.target, sess.hasTarget = sess.window.target(sess.strategy.Adaptive, sess.pockets)
+	}
+	for k, winningNumber := range winningNumbers {
+		sess.settle(k, winningNumber, placed)
+		if result.PocketCounts != nil {
+			result.PocketCounts[winningNumber]++
+		}
+	}
+	if sess.window != nil {
+		for _, winningNumber := range winningNumbers {
+			sess.window.add(winningNumber)
+		}
+	}
+	if trigger != nil {
+		sess.updateTrigger(trigger, winningNumbers[0], sess.bankroll-bankrollBefore)
+	}
+
+	if logging {
+		// Wheels past the first can skip bets lower in the strategy than
+		// the first wheel did
+		sort.Ints(sess.skipped)
+		record := SpinRecord{
+			Spin:          result.SpinsPlayed,
+			WinningNumber: winningNumbers[0],
+			Stakes:        placed,
+			Skipped:       sess.skipped,
+			NetChange:     FromCents(sess.bankroll - bankrollBefore),
+			Bankroll:      FromCents(sess.bankroll),
+			Spectated:     sess.spectating,
+		}
+		sess.skipped = nil
+		if len(winningNumbers) > 1 {
+			record.WinningNumbers = append([]int(nil), winningNumbers...)
+		}
+		if sess.hasTarget {
+			target := sess.target
+			record.Target = &target
+		}
+		if sess.sink != nil {
+			sess.sink.write(record)
+		} else {
+			result.SpinLog = append(result.SpinLog, record)
+		}
+	}
+
+	// A spin counts toward a streak by its net result across all bets, and a
+	// spin that breaks even ends both kinds of streak, unless it was only
+	// watched
+	switch net := sess.bankroll - bankrollBefore; {
+	case net > 0:
+		sess.winStreak++
+		sess.lossStreak = 0
+	case net < 0:
+		sess.lossStreak++
+		sess.winStreak = 0
+	case !sess.spectating:
+		sess.winStreak, sess.lossStreak = 0, 0
+	}
+	if sess.winStreak > result.LongestWinStreak {
+		result.LongestWinStreak = sess.winStreak
+	}
+	if sess.lossStreak > result.LongestLossStreak {
+		result.LongestLossStreak = sess.lossStreak
+	}
+
+	if sess.bankroll > sess.peak {
+		sess.peak = sess.bankroll
+	}
+	if sess.bankroll < sess.lowest {
+		sess.lowest = sess.bankroll
+	}
+	sess.advancePhase()
+	// Only a losing round can leave the bets out of reach
+	if result.BustSpin == 0 && sess.bankroll < bankrollBefore && !sess.canAfford() {
+		result.BustSpin = result.SpinsPlayed
+	}
+}
+
+// updateTrigger follows the trigger's streak on the first wheel. Once the
+// streak is long enough the bets play from the next spin on, and a spin bet
+// with a net win ends the episode and re-arms the trigger.
+func (sess *session) updateTrigger(trigger *Trigger, winningNumber int, net int64) {
+	if sess.triggered {
+		if !sess.spectating && net > 0 {
+			sess.triggered = false
+			sess.triggerStreak = 0
+		}
+		return
+	}
+	if !trigger.matches(winningNumber) {
+		sess.triggerStreak = 0
+		return
+	}
+	sess.triggerStreak++
+	if sess.triggerStreak >= trigger.Count {
+		sess.triggered = true
+		sess.result.TriggersFired++
+	}
+}
+
+// advancePhase moves on to the next phase once the current one's until
+// condition has been met. The last phase plays out the rest of the run.
+func (sess *session) advancePhase() {
+	phases := sess.strategy.Phases
+	if sess.phase == 0 || sess.phase == len(phases) {
+		return
+	}
+	phase := phases[sess.phase-1]
+	profitReached := phase.UntilProfit > 0 && sess.bankroll-sess.phaseBankroll >= ToCents(phase.UntilProfit)
+	spinsReached := phase.UntilSpins > 0 && sess.result.SpinsPlayed-sess.phaseSpin >= phase.UntilSpins
+	if profitReached || spinsReached {
+		sess.phase++
+		sess.phaseBankroll, sess.phaseSpin = sess.bankroll, sess.result.SpinsPlayed
+		sess.result.PhaseStarts = append(sess.result.PhaseStarts, sess.result.SpinsPlayed)
+	}
+}
+
+// settle places every bet the bankroll covers on wheel k and settles it
+// against the wheel's winning number. Bets are settled one at a time and
+// never against each other, so bets that overlap, like a dozen and a column
+// sharing a number, each pay out in full when that number comes up.
+func (sess *session) settle(k int, winningNumber int, placed []float64) {
+	result := sess.result
+	for j, bet := range sess.strategy.Bets {
+		if sess.imprisoned[k][j] > 0 {
+			sess.release(k, j, winningNumber)
+			continue
+		}
+		// Stakes already on the table are still decided on a watched spin,
+		// and the schedule moves on only with the spins that are bet
+		if sess.spectating || !bet.inPhase(sess.phase) || !sess.strategy.Schedule.Active(bet.Group, result.SpinsPlayed-result.SpinsSpectated-1) {
+			continue
+		}
+		if bet.Type == "number" && sess.hasTarget {
+			bet.Value = sess.target
+		}
+		stake := sess.placeStake(j)
+		if stake <= 0 {
+			continue
+		}
+		if sess.bankroll < stake {
+			// Skip this bet if we don't have enough money
+			result.BetsSkipped++
+			result.PerBet[j].TimesSkipped++
+			if placed != nil && !contains(sess.skipped, j) {
+				sess.skipped = append(sess.skipped, j)
+			}
+			continue
+		}
+		if placed != nil {
+			placed[j] += FromCents(stake)
+		}
+
+		sess.bankroll -= stake
+		sess.wagered += stake
+		result.PerBet[j].TimesPlaced++
+		sess.betWagered[j] += stake
+		if winningNumber == 0 && isEvenMoney(bet.Type) {
+			switch sess.strategy.ZeroRule {
+			case "partage":
+				// Half the stake comes back, and the bet counts as lost
+				sess.bankroll += stake / 2
+				sess.betNet[j] += stake/2 - stake
+				result.BetsLost++
+				sess.pending[j] = sess.progressions[j].NextBet(sess.base(j), Loss)
+				continue
+			case "prison":
+				// The bet stays on the table and the wheel's next spin settles it
+				sess.imprisoned[k][j] = stake
+				sess.betNet[j] -= stake
+				continue
+			}
+		}
+		multiple := sess.strategy.payoutMultiple(bet, winningNumber)
+		sess.bankroll += stake * multiple
+		sess.betNet[j] += stake*multiple - stake
+
+		outcome := Loss
+		if multiple > 0 {
+			outcome = Win
+			result.BetsWon++
+			result.PerBet[j].TimesWon++
+		} else {
+			result.BetsLost++
+		}
+		sess.pending[j] = sess.progressions[j].NextBet(sess.base(j), outcome)
+	}
+}
+
+// release settles bet j's imprisoned stake on wheel k. The stake is returned
+// without winnings if the bet wins on this spin, and lost otherwise,
+// including on a second zero. A returned stake leaves the progression where
+// it was.
+func (sess *session) release(k, j int, winningNumber int) {
+	stake := sess.imprisoned[k][j]
+	sess.imprisoned[k][j] = 0
+	if sess.strategy.payoutMultiple(sess.strategy.Bets[j], winningNumber) > 0 {
+		sess.bankroll += stake
+		sess.betNet[j] += stake
+		return
+	}
+	sess.result.BetsLost++
+	sess.pending[j] = sess.progressions[j].NextBet(sess.base(j), Loss)
+}
+
+// close fills in the money totals of the result
+func (sess *session) close() *SimulationResult {
+	result := sess.result
+	result.FinalBankroll = FromCents(sess.bankroll)
+	result.PeakBankroll = FromCents(sess.peak)
+	result.MinBankroll = FromCents(sess.lowest)
+	result.TotalWagered = FromCents(sess.wagered)
+	// Every cent the bankroll gained or lost went through a bet, so whatever
+	// was wagered and isn't missing from the bankroll was paid back
+	returned := sess.wagered + sess.bankroll - ToCents(sess.strategy.InitialBankroll)
+	result.TotalReturned = FromCents(returned)
+	result.HouseProfit = FromCents(ToCents(sess.strategy.InitialBankroll) - sess.bankroll)
+	if sess.wagered > 0 {
+		result.RTP = 100 * float64(returned) / float64(sess.wagered)
+	}
+	for j, stats := range result.PerBet {
+		stats.TotalWagered = FromCents(sess.betWagered[j])
+		stats.NetProfit = FromCents(sess.betNet[j])
+	}
+	result.WentBust = len(sess.strategy.Bets) > 0 && !sess.canAfford()
+	return result
+}
+
+// base returns the amount bet j's progression scales: its fixed amount, or a
+// single multiple of its percentage
+func (sess *session) base(j int) float64 {
+	if sess.strategy.Bets[j].Percent > 0 {
+		return 1
+	}
+	return sess.strategy.Bets[j].Amount
+}
+
+// rawStake returns the stake bet j's progression asks for right now, before
+// the table limits are applied
+func (sess *session) rawStake(j int) int64 {
+	bet := sess.strategy.Bets[j]
+	if bet.Percent > 0 {
+		return ToCents(FromCents(sess.bankroll) * bet.Percent / 100 * sess.pending[j])
+	}
+	return ToCents(sess.pending[j])
+}
+
+// clamp keeps a stake within the table limits
+func (sess *session) clamp(stake int64) int64 {
+	if tableMax := ToCents(sess.strategy.TableMax); tableMax > 0 && stake > tableMax {
+		stake = tableMax
+	}
+	if tableMin := ToCents(sess.strategy.TableMin); stake < tableMin {
+		stake = tableMin
+	}
+	return stake
+}
+
+// placeStake works out the stake for bet j, counting every time the table
+// limits get in the way and giving up on the progression when the limit
+// policy says so
+func (sess *session) placeStake(j int) int64 {
+	stake := sess.rawStake(j)
+	if tableMax := ToCents(sess.strategy.TableMax); tableMax > 0 && stake > tableMax {
+		sess.result.TableLimitHit++
+		if sess.strategy.LimitPolicy == "abandon" {
+			// Start the progression over from the base stake
+			sess.progressions[j] = newProgression(sess.strategy, sess.strategy.progressionFor(j))
+			sess.pending[j] = sess.progressions[j].NextBet(sess.base(j), NoResult)
+			stake = sess.rawStake(j)
+		}
+	}
+	if tableMin := ToCents(sess.strategy.TableMin); stake < tableMin {
+		sess.result.TableLimitHit++
+	}
+	return sess.clamp(stake)
+}
+
+// canAfford reports whether the bankroll covers at least one of the bets
+func (sess *session) canAfford() bool {
+	for j, bet := range sess.strategy.Bets {
+		if !bet.inPhase(sess.phase) {
+			continue
+		}
+		stake := sess.clamp(sess.rawStake(j))
+		if stake > 0 && sess.bankroll >= stake {
+			return true
+		}
+	}
+	return false
+}
+
+// ToCents converts a dollar amount to whole cents, rounding to the nearest cent
+func ToCents(dollars float64) int64 {
+	return int64(math.Round(dollars * 100))
+}
+
+// FromCents converts whole cents to a dollar amount
+func FromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// MoneyFormat is how amounts of money are written out: the currency symbol
+// and the number of decimal places shown. Accounting is always done in whole
+// cents whatever the format, so it only changes how results read.
+type MoneyFormat struct {
+	Symbol string // Written before the amount, or after it for words such as "units"
+	Places int    // Decimal places shown, from 0 to 2
+}
+
+// DefaultMoneyFormat writes dollars and cents, as in $1,234.56
+var DefaultMoneyFormat = MoneyFormat{Symbol: "$", Places: 2}
+
+// FormatMoney renders whole cents as dollars with the thousands grouped, as
+// in $1,234.56, and a leading minus for losses, as in -$50.00
+func FormatMoney(cents int64) string {
+	return DefaultMoneyFormat.Format(cents)
+}
+
+// Format renders whole cents in the format with the thousands grouped,
+// rounding half away from zero when fewer than two places are shown
+func (f MoneyFormat) Format(cents int64) string {
+	magnitude := uint64(cents)
+	if cents < 0 {
+		magnitude = uint64(-cents)
+	}
+	scale := uint64(1)
+	for i := f.Places; i < 2; i++ {
+		scale *= 10
+	}
+	magnitude = (magnitude + scale/2) / scale
+	shift := uint64(100) / scale
+	digits := strconv.FormatUint(magnitude/shift, 10)
+	var grouped strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(d)
+	}
+	if f.Places > 0 {
+		fmt.Fprintf(&grouped, ".%0*d", f.Places, magnitude%shift)
+	}
+	return f.withSymbol(cents < 0 && magnitude > 0, grouped.String())
+}
+
+// FormatFine renders an amount to two more places than the format has, for
+// averages too small to show in whole cents
+func (f MoneyFormat) FormatFine(amount float64) string {
+	return f.withSymbol(amount < 0, strconv.FormatFloat(math.Abs(amount), 'f', f.Places+2, 64))
+}
+
+// withSymbol puts the currency symbol and any minus sign around the digits of
+// an amount. Symbols holding letters, such as "EUR" or "units", follow the
+// amount after a space, and any other symbol comes right before it.
+func (f MoneyFormat) withSymbol(negative bool, digits string) string {
+	sign := ""
+	if negative {
+		sign = "-"
+	}
+	if strings.IndexFunc(f.Symbol, unicode.IsLetter) >= 0 {
+		return sign + digits + " " + f.Symbol
+	}
+	return sign + f.Symbol + digits
+}
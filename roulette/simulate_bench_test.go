@@ -0,0 +1,92 @@
+package roulette
+
+import (
+	"testing"
+)
+
+// benchSpins is the length of every benchmarked run
+const benchSpins = 100_000
+
+// benchmarkSimulate runs the strategy for benchSpins spins from a fixed seed
+// on every iteration
+func benchmarkSimulate(b *testing.B, text string) {
+	strategy, err := ParseStrategy(text)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SimulateSeeded(strategy, benchSpins, 1)
+	}
+}
+
+func BenchmarkSimulateEvenMoney(b *testing.B) {
+	benchmarkSimulate(b, "bankroll: 100000000\nbet: red, 0, 10\n")
+}
+
+func BenchmarkSimulateFullCoverage(b *testing.B) {
+	benchmarkSimulate(b, `bankroll: 100000000
+bet: dozen, 1, 10
+bet: dozen, 2, 10
+bet: dozen, 3, 10
+bet: number, 0, 1
+bet: number, 00, 1
+`)
+}
+
+func BenchmarkSimulateMartingale(b *testing.B) {
+	benchmarkSimulate(b, "bankroll: 100000000\nprogression: martingale\nmartingale_max_steps: 10\nbet: red, 0, 10\n")
+}
+
+func BenchmarkSpin(b *testing.B) {
+	wheel := NewSeededWheel(1)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		wheel.Spin()
+	}
+}
+
+func TestColourChecksDontAllocate(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 100\nbet: red, 0, 10\nbet: black, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	red, black := strategy.Bets[0], strategy.Bets[1]
+	n := 0
+	allocs := testing.AllocsPerRun(1000, func() {
+		n = (n + 1) % 37
+		strategy.payoutMultiple(red, n)
+		strategy.payoutMultiple(black, n)
+		betWins(red, n)
+		betWins(black, n)
+	})
+	if allocs != 0 {
+		t.Errorf("settling red and black allocated %v times a spin, want 0", allocs)
+	}
+}
+
+func TestSpinsDontAllocate(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 100000000\nbet: red, 0, 10\nbet: black, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := testing.AllocsPerRun(5, func() { SimulateSeeded(strategy, 1000, 1) })
+	long := testing.AllocsPerRun(5, func() { SimulateSeeded(strategy, 100_000, 1) })
+	if long != short {
+		t.Errorf("100000 spins allocated %v times and 1000 spins %v, want the same", long, short)
+	}
+}
+
+func BenchmarkSettleRedBlack(b *testing.B) {
+	strategy, err := ParseStrategy("bankroll: 100\nbet: red, 0, 10\nbet: black, 0, 10\n")
+	if err != nil {
+		b.Fatal(err)
+	}
+	red, black := strategy.Bets[0], strategy.Bets[1]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		strategy.payoutMultiple(red, i%37)
+		strategy.payoutMultiple(black, i%37)
+	}
+}
@@ -0,0 +1,905 @@
+package roulette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// replay parses the strategy and plays it against the given spins
+func replay(t *testing.T, text string, spins ...int) *SimulationResult {
+	t.Helper()
+	strategy, err := ParseStrategy(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := SimulateWithSpins(strategy, spins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestTakeProfitEndsEarly(t *testing.T) {
+	result := replay(t, "bankroll: 100\ntake_profit: 120\nstop_loss: 50\nbet: red, 0, 10\n", 1, 1, 1, 1, 1)
+	if result.SpinsPlayed != 2 || result.FinalBankroll != 120 || result.Outcome != ReachedTakeProfit {
+		t.Errorf("played %d spins leaving %v ending %v, want 2 spins leaving 120 ending %v",
+			result.SpinsPlayed, result.FinalBankroll, result.Outcome, ReachedTakeProfit)
+	}
+}
+
+func TestStopLossEndsEarly(t *testing.T) {
+	result := replay(t, "bankroll: 100\ntake_profit: 120\nstop_loss: 70\nbet: red, 0, 10\n", 2, 2, 2, 2, 2)
+	if result.SpinsPlayed != 3 || result.FinalBankroll != 70 || result.Outcome != HitStopLoss {
+		t.Errorf("played %d spins leaving %v ending %v, want 3 spins leaving 70 ending %v",
+			result.SpinsPlayed, result.FinalBankroll, result.Outcome, HitStopLoss)
+	}
+}
+
+func TestNoThresholdPlaysEverySpin(t *testing.T) {
+	result := replay(t, "bankroll: 100\ntake_profit: 200\nstop_loss: 10\nbet: red, 0, 10\n", 1, 2, 1, 2, 1)
+	if result.SpinsPlayed != 5 || result.FinalBankroll != 110 {
+		t.Errorf("played %d spins leaving %v, want 5 spins leaving 110", result.SpinsPlayed, result.FinalBankroll)
+	}
+}
+
+func TestSeededResultCountersAddUp(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 100000\nbet: red, 0, 10\nbet: number, 17, 5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := SimulateSeeded(strategy, 1000, 42)
+	if result.SpinsPlayed != 1000 {
+		t.Fatalf("played %d spins, want 1000", result.SpinsPlayed)
+	}
+	if got := result.BetsWon + result.BetsLost; got != 2000 {
+		t.Errorf("bets won + lost = %d, want 2000", got)
+	}
+	if result.TotalWagered != 15000 {
+		t.Errorf("total wagered = %v, want 15000", result.TotalWagered)
+	}
+	if got := 100000 - result.TotalWagered + result.TotalReturned; got != result.FinalBankroll {
+		t.Errorf("bankroll - wagered + returned = %v, want the final bankroll %v", got, result.FinalBankroll)
+	}
+	if result.PeakBankroll < result.FinalBankroll || result.MinBankroll > result.FinalBankroll {
+		t.Errorf("final bankroll %v is outside the peak %v and low %v", result.FinalBankroll, result.PeakBankroll, result.MinBankroll)
+	}
+	if result.WentBust {
+		t.Error("went bust with a bankroll that covers every spin")
+	}
+}
+
+func TestSimulateSeededRepeats(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 1000\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := SimulateSeeded(strategy, 200, 5)
+	b := SimulateSeeded(strategy, 200, 5)
+	if !reflect.DeepEqual(a, b) {
+		t.Error("two runs with the same seed came out different")
+	}
+	if reflect.DeepEqual(a, SimulateSeeded(strategy, 200, 6)) {
+		t.Error("runs with different seeds came out the same")
+	}
+
+	first, second := NewSeededWheel(5), NewSeededWheel(5)
+	for i := 0; i < 100; i++ {
+		if x, y := first.Spin(), second.Spin(); x != y {
+			t.Fatalf("spin %d: wheels seeded alike landed on %d and %d", i, x, y)
+		}
+	}
+}
+
+func TestSpinLogNetChangesAddUp(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 500\nprogression: martingale\nbet: red, 0, 5\nbet: dozen, 2, 5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := SimulateWithOptions(strategy, SimulationOptions{NumGames: 300, Seed: 21, RecordSpins: true})
+	if len(result.SpinLog) != result.SpinsPlayed {
+		t.Fatalf("logged %d spins, want %d", len(result.SpinLog), result.SpinsPlayed)
+	}
+	net := 0.0
+	for i, record := range result.SpinLog {
+		if record.Spin != i+1 {
+			t.Errorf("record %d has spin %d", i, record.Spin)
+		}
+		net += record.NetChange
+		if math.Abs(500+net-record.Bankroll) > 1e-9 {
+			t.Fatalf("spin %d: running bankroll %v, want %v", record.Spin, record.Bankroll, 500+net)
+		}
+	}
+	if math.Abs(net-(result.FinalBankroll-500)) > 1e-9 {
+		t.Errorf("net changes add up to %v, want %v", net, result.FinalBankroll-500)
+	}
+
+	quiet := SimulateWithOptions(strategy, SimulationOptions{NumGames: 300, Seed: 21})
+	if quiet.SpinLog != nil {
+		t.Error("spin log kept without RecordSpins")
+	}
+}
+
+func TestResultJSONRoundTrip(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 200\nbet: red, 0, 10\nbet: number, 00, 2.5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := SimulateWithOptions(strategy, SimulationOptions{NumGames: 50, Seed: 9, RecordSpins: true, CountPockets: true})
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded SimulationResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, &decoded) {
+		t.Errorf("result changed going through JSON:\n got %+v\nwant %+v", decoded, *result)
+	}
+	if !strings.Contains(string(data), `"spin_log":[{"spin":1,`) {
+		t.Errorf("spin log isn't written with snake_case keys: %s", data)
+	}
+	if !strings.Contains(string(data), `"outcome":"completed"`) {
+		t.Errorf("outcome isn't written by name: %s", data)
+	}
+
+	mc := RunMonteCarloSeeded(strategy, 50, 20, 9)
+	data, err = json.Marshal(mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decodedMC MonteCarloResult
+	if err := json.Unmarshal(data, &decodedMC); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mc, &decodedMC) {
+		t.Errorf("monte carlo result changed going through JSON:\n got %+v\nwant %+v", decodedMC, *mc)
+	}
+}
+
+func TestCentConversions(t *testing.T) {
+	for _, tt := range []struct {
+		dollars float64
+		cents   int64
+	}{{5.50, 550}, {0.1 + 0.2, 30}, {19.99, 1999}, {-2.5, -250}} {
+		if got := ToCents(tt.dollars); got != tt.cents {
+			t.Errorf("ToCents(%v) = %d, want %d", tt.dollars, got, tt.cents)
+		}
+	}
+	if got := FromCents(550); got != 5.5 {
+		t.Errorf("FromCents(550) = %v, want 5.5", got)
+	}
+}
+
+func TestExactCentAccounting(t *testing.T) {
+	// In float64, 0.30 - 0.10 - 0.10 leaves slightly less than 0.10, which
+	// would skip the last bet
+	result := replay(t, "bankroll: 0.30\nbet: red, 0, 0.10\n", 2, 2, 2)
+	if result.BetsLost != 3 || result.BetsSkipped != 0 || result.FinalBankroll != 0 {
+		t.Errorf("lost %d bets and skipped %d leaving %v, want 3 lost, none skipped and 0 left",
+			result.BetsLost, result.BetsSkipped, result.FinalBankroll)
+	}
+
+	strategy, err := ParseStrategy("bankroll: 1000\nbet: even, 0, 5.50\nbet: number, 00, 0.10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := SimulateWithOptions(strategy, SimulationOptions{NumGames: 100_000, Seed: 6, RecordSpins: true})
+	net := int64(0)
+	for _, record := range long.SpinLog {
+		net += ToCents(record.NetChange)
+	}
+	if got := ToCents(long.FinalBankroll) - 100000; got != net {
+		t.Errorf("final bankroll moved %d cents, but the spins netted %d", got, net)
+	}
+	if cents := long.FinalBankroll * 100; cents != math.Round(cents) {
+		t.Errorf("final bankroll %v isn't a whole number of cents", long.FinalBankroll)
+	}
+}
+
+func TestPerBetCountersSumToTotals(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 5000\nbet: black, 0, 10\nbet: column, 2, 4\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := SimulateSeeded(strategy, 500, 13)
+	if len(result.PerBet) != 2 {
+		t.Fatalf("per-bet stats for %d bets, want 2", len(result.PerBet))
+	}
+	placed, won := 0, 0
+	wagered, net := 0.0, 0.0
+	for _, stats := range result.PerBet {
+		placed += stats.TimesPlaced
+		won += stats.TimesWon
+		wagered += stats.TotalWagered
+		net += stats.NetProfit
+	}
+	if placed != result.BetsWon+result.BetsLost || won != result.BetsWon {
+		t.Errorf("placed %d and won %d, want %d and %d", placed, won, result.BetsWon+result.BetsLost, result.BetsWon)
+	}
+	if wagered != result.TotalWagered {
+		t.Errorf("per-bet wagers add up to %v, want %v", wagered, result.TotalWagered)
+	}
+	if math.Abs(net-(result.FinalBankroll-5000)) > 1e-6 {
+		t.Errorf("per-bet profits add up to %v, want %v", net, result.FinalBankroll-5000)
+	}
+	if result.PerBet[0].TotalWagered != 5000 || result.PerBet[1].TotalWagered != 2000 {
+		t.Errorf("wagered %v and %v, want 5000 and 2000", result.PerBet[0].TotalWagered, result.PerBet[1].TotalWagered)
+	}
+}
+
+func TestBustPolicies(t *testing.T) {
+	stopped := replay(t, "bankroll: 15\nbust_policy: stop\nbet: red, 0, 10\n", 2, 1, 1)
+	if stopped.SpinsPlayed != 1 || !stopped.StoppedOnBust || !stopped.WentBust || stopped.Outcome != WentBust {
+		t.Errorf("stop: played %d spins, stopped %v, bust %v; want 1 spin stopping bust",
+			stopped.SpinsPlayed, stopped.StoppedOnBust, stopped.WentBust)
+	}
+
+	skipped := replay(t, "bankroll: 15\nbust_policy: skip\nbet: red, 0, 10\n", 2, 1, 1)
+	if skipped.SpinsPlayed != 3 || skipped.StoppedOnBust || skipped.Outcome != CompletedAllSpins {
+		t.Errorf("skip: played %d spins, stopped %v; want 3 spins", skipped.SpinsPlayed, skipped.StoppedOnBust)
+	}
+	if skipped.BetsWon+skipped.BetsLost != 1 || skipped.FinalBankroll != 5 || !skipped.WentBust {
+		t.Errorf("skip: settled %d bets leaving %v, want 1 settled and 5 left", skipped.BetsWon+skipped.BetsLost, skipped.FinalBankroll)
+	}
+	if skipped.BetsSkipped != 2 {
+		t.Errorf("skip: skipped %d bets, want 2", skipped.BetsSkipped)
+	}
+}
+
+func TestLongestStreaks(t *testing.T) {
+	// 1 and 3 are red, 2 and 4 black; 0 loses both bets
+	result := replay(t, "bankroll: 1000\nbet: red, 0, 10\n", 1, 3, 2, 4, 0, 2, 1, 1, 1, 3, 2)
+	if result.LongestWinStreak != 4 || result.LongestLossStreak != 4 {
+		t.Errorf("streaks = %d won, %d lost; want 4 and 4", result.LongestWinStreak, result.LongestLossStreak)
+	}
+
+	// Red with the first dozen wins a spin overall on any red, breaks even
+	// on a black in 1-12, which ends both streaks, and loses on other blacks
+	result = replay(t, "bankroll: 1000\nbet: red, 0, 10\nbet: dozen, 1, 5\n", 1, 3, 2, 19, 20, 22, 24)
+	if result.LongestWinStreak != 2 || result.LongestLossStreak != 3 {
+		t.Errorf("multi-bet streaks = %d won, %d lost; want 2 and 3", result.LongestWinStreak, result.LongestLossStreak)
+	}
+}
+
+func TestScheduleRotatesGroups(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 1000\nschedule: a b b\ngroup: a\nbet: red, 0, 10\ngroup: b\nbet: black, 0, 5\ngroup:\nbet: number, 17, 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flat stakes don't depend on the spins, so any seed will do
+	result := SimulateWithOptions(strategy, SimulationOptions{NumGames: 7, Seed: 1, RecordSpins: true})
+	want := [][]float64{
+		{10, 0, 1},
+		{0, 5, 1},
+		{0, 5, 1},
+		{10, 0, 1},
+		{0, 5, 1},
+		{0, 5, 1},
+		{10, 0, 1},
+	}
+	for i, record := range result.SpinLog {
+		if !reflect.DeepEqual(record.Stakes, want[i]) {
+			t.Errorf("spin %d: stakes = %v, want %v", record.Spin, record.Stakes, want[i])
+		}
+	}
+	if result.PerBet[0].TimesPlaced != 3 || result.PerBet[1].TimesPlaced != 4 || result.PerBet[2].TimesPlaced != 7 {
+		t.Errorf("placed %d, %d and %d times, want 3, 4 and 7",
+			result.PerBet[0].TimesPlaced, result.PerBet[1].TimesPlaced, result.PerBet[2].TimesPlaced)
+	}
+
+	if _, err := ParseStrategy("bankroll: 1000\nschedule: a c\ngroup: a\nbet: red, 0, 10\n"); err == nil {
+		t.Error("a schedule naming a group with no bets was accepted")
+	}
+	if !(*Schedule)(nil).Active("a", 3) || !(&Schedule{Groups: []string{"a"}}).Active("", 0) {
+		t.Error("bets should play every spin without a schedule or a group")
+	}
+}
+
+func TestSimulateWithSpinsFinalBankroll(t *testing.T) {
+	// 1 and 3 are red and net 9, the black 17 nets 25, 00 and the black 2
+	// lose both bets
+	result := replay(t, "bankroll: 100\nbet: red, 0, 10\nbet: number, 17, 1\n", 1, 17, DoubleZero, 3, 2)
+	if result.FinalBankroll != 121 || result.SpinsPlayed != 5 {
+		t.Errorf("final bankroll %v after %d spins, want 121 after 5", result.FinalBankroll, result.SpinsPlayed)
+	}
+
+	strategy, err := ParseStrategy("bankroll: 100\nwheel: european\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SimulateWithSpins(strategy, []int{1, DoubleZero}); err == nil || !strings.Contains(err.Error(), "spin 2: 00") {
+		t.Errorf("got error %v, want one for 00 on spin 2", err)
+	}
+	if _, err := SimulateWithSpins(strategy, []int{37}); err == nil {
+		t.Error("pocket 37 was accepted")
+	}
+}
+
+func TestPartageReturnsHalfOnZero(t *testing.T) {
+	result := replay(t, "bankroll: 100\nwheel: european\nzero_rule: partage\nbet: red, 0, 10\nbet: number, 5, 2\n", 0)
+	if result.FinalBankroll != 93 {
+		t.Errorf("final bankroll = %v, want 93 with half the red stake back", result.FinalBankroll)
+	}
+	if result.PerBet[0].NetProfit != -5 || result.PerBet[1].NetProfit != -2 {
+		t.Errorf("bets netted %v and %v, want -5 and -2", result.PerBet[0].NetProfit, result.PerBet[1].NetProfit)
+	}
+	if _, err := ParseStrategy("bankroll: 100\nzero_rule: partage\nbet: red, 0, 10\n"); err == nil {
+		t.Error("partage on the american wheel was accepted")
+	}
+}
+
+func TestPrisonReleasesOnWin(t *testing.T) {
+	// 1 is red: the imprisoned stake comes back without winnings, and the
+	// bet is placed again on the spin after
+	result := replay(t, "bankroll: 100\nwheel: european\nzero_rule: prison\nbet: red, 0, 10\n", 0, 1, 1)
+	if result.FinalBankroll != 110 || result.PerBet[0].TimesPlaced != 2 {
+		t.Errorf("final bankroll %v after %d bets, want 110 after 2", result.FinalBankroll, result.PerBet[0].TimesPlaced)
+	}
+	if result.PerBet[0].NetProfit != 10 {
+		t.Errorf("net profit = %v, want 10", result.PerBet[0].NetProfit)
+	}
+}
+
+func TestPrisonLosesOnLossOrSecondZero(t *testing.T) {
+	for _, spins := range [][]int{{0, 2}, {0, 0}} {
+		result := replay(t, "bankroll: 100\nwheel: european\nzero_rule: prison\nbet: red, 0, 10\n", spins...)
+		if result.FinalBankroll != 90 || result.BetsLost != 1 || result.PerBet[0].TimesPlaced != 1 {
+			t.Errorf("spins %v: final bankroll %v with %d lost of %d placed, want 90 with 1 of 1",
+				spins, result.FinalBankroll, result.BetsLost, result.PerBet[0].TimesPlaced)
+		}
+	}
+}
+
+func TestPartageEdgeMatchesLongRun(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 100000000\nwheel: european\nzero_rule: partage\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strategy.ExpectedValuePerRound()
+	if math.Abs(want-(-5.0/37)) > 1e-9 {
+		t.Errorf("expected value = %v, want %v, half the usual edge", want, -5.0/37)
+	}
+	const spins = 1_000_000
+	result := SimulateSeeded(strategy, spins, 46)
+	got := (result.FinalBankroll - strategy.InitialBankroll) / spins
+	if math.Abs(got-want) > 0.04 {
+		t.Errorf("long run lost %.4f a spin, want about %.4f", got, want)
+	}
+}
+
+func TestSimulateContextStopsEarly(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 1000000000\nbet: red, 0, 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	const spins = 1_000_000_000
+	result, err := SimulateContext(ctx, strategy, spins)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want context.DeadlineExceeded", err)
+	}
+	if result == nil || result.SpinsPlayed == 0 || result.SpinsPlayed >= spins || result.Outcome != Interrupted {
+		t.Fatalf("got %+v, want a partial result", result)
+	}
+	if got := result.BetsWon + result.BetsLost; got != result.SpinsPlayed {
+		t.Errorf("settled %d bets in %d spins", got, result.SpinsPlayed)
+	}
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if _, err := SimulateContext(canceled, strategy, 1000); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v from a canceled context, want context.Canceled", err)
+	}
+}
+
+func TestProgressCallback(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 100000\nbet: red, 0, 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var done []int
+	result := SimulateWithOptions(strategy, SimulationOptions{
+		NumGames:      1050,
+		Seed:          3,
+		ProgressEvery: 100,
+		ProgressFunc: func(spinsDone int, bankroll float64) {
+			done = append(done, spinsDone)
+		},
+	})
+	if len(done) != 10 {
+		t.Fatalf("callback fired %d times, want 10", len(done))
+	}
+	for i, spins := range done {
+		if spins != (i+1)*100 {
+			t.Errorf("call %d reported %d spins, want %d", i+1, spins, (i+1)*100)
+		}
+	}
+	if result.SpinsPlayed != 1050 {
+		t.Errorf("played %d spins, want 1050", result.SpinsPlayed)
+	}
+
+	// Neither a missing callback nor a zero interval should call anything
+	SimulateWithOptions(strategy, SimulationOptions{NumGames: 100, Seed: 3, ProgressEvery: 10})
+	called := false
+	SimulateWithOptions(strategy, SimulationOptions{NumGames: 100, Seed: 3, ProgressFunc: func(int, float64) { called = true }})
+	if called {
+		t.Error("callback fired with ProgressEvery unset")
+	}
+}
+
+func TestMaxLossStreakBreaker(t *testing.T) {
+	result := replay(t, "bankroll: 1000\nmax_loss_streak: 3\nbet: red, 0, 10\n", 2, 2, 1, 2, 2, 2, 2, 2)
+	if result.SpinsPlayed != 6 || result.Outcome != HitLossStreakLimit {
+		t.Errorf("played %d spins stopping for %v, want 6 stopping for %v", result.SpinsPlayed, result.Outcome, HitLossStreakLimit)
+	}
+	if result.FinalBankroll != 960 {
+		t.Errorf("final bankroll = %v, want 960", result.FinalBankroll)
+	}
+
+	// The stop-loss is reached on the same spin and takes precedence
+	result = replay(t, "bankroll: 1000\nmax_loss_streak: 3\nstop_loss: 970\nbet: red, 0, 10\n", 2, 2, 2, 2)
+	if result.SpinsPlayed != 3 || result.Outcome != HitStopLoss {
+		t.Errorf("played %d spins stopping for %v, want 3 stopping for %v", result.SpinsPlayed, result.Outcome, HitStopLoss)
+	}
+	result = replay(t, "bankroll: 1000\nmax_loss_streak: 3\nstop_loss: 980\nbet: red, 0, 10\n", 2, 2, 2, 2)
+	if result.SpinsPlayed != 2 || result.Outcome != HitStopLoss {
+		t.Errorf("played %d spins stopping for %v, want 2 stopping for %v", result.SpinsPlayed, result.Outcome, HitStopLoss)
+	}
+}
+
+func TestMaxSpinsCapsRun(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 100000\nbet: red, 0, 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := SimulateWithOptions(strategy, SimulationOptions{NumGames: 1000, Seed: 1, MaxSpins: 100})
+	if result.SpinsPlayed != 100 || result.Outcome != HitSpinLimit {
+		t.Errorf("played %d spins stopping for %v, want 100 stopping for %v", result.SpinsPlayed, result.Outcome, HitSpinLimit)
+	}
+	result = SimulateWithOptions(strategy, SimulationOptions{NumGames: 100, Seed: 1, MaxSpins: 100})
+	if result.SpinsPlayed != 100 || result.Outcome != CompletedAllSpins {
+		t.Errorf("played %d spins stopping for %v, want all 100 played", result.SpinsPlayed, result.Outcome)
+	}
+}
+
+func TestOverlappingBetsSettleIndependently(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 100\nbet: dozen, 1, 10\nbet: column, 1, 10\nbet: black, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1 is red and sits in both the first dozen and the first column: the
+	// $30 staked comes back as $30 from each winning bet
+	result, err := ReplayWithOptions(strategy, []int{1}, SimulationOptions{RecordSpins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.SpinLog[0].NetChange; got != 30 {
+		t.Errorf("net change = %v, want 30", got)
+	}
+	if result.FinalBankroll != 130 {
+		t.Errorf("final bankroll = %v, want 130", result.FinalBankroll)
+	}
+	for j, want := range []float64{20, 20, -10} {
+		if got := result.PerBet[j].NetProfit; got != want {
+			t.Errorf("bet %d netted %v, want %v", j, got, want)
+		}
+	}
+	if result.BetsWon != 2 || result.BetsLost != 1 {
+		t.Errorf("won %d and lost %d bets, want 2 and 1", result.BetsWon, result.BetsLost)
+	}
+}
+
+func TestRTPConverges(t *testing.T) {
+	for _, tt := range []struct {
+		wheel string
+		want  float64
+	}{{"american", 100 * 36.0 / 38}, {"european", 100 * 36.0 / 37}} {
+		strategy, err := ParseStrategy("bankroll: 100000000\nwheel: " + tt.wheel + "\nbet: even, 0, 1\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := SimulateSeeded(strategy, 1_000_000, 58)
+		if math.Abs(result.RTP-tt.want) > 0.4 {
+			t.Errorf("%s RTP = %.2f%%, want about %.2f%%", tt.wheel, result.RTP, tt.want)
+		}
+		if got := 100 * result.TotalReturned / result.TotalWagered; math.Abs(got-result.RTP) > 1e-9 {
+			t.Errorf("%s RTP = %v, but returned over wagered is %v", tt.wheel, result.RTP, got)
+		}
+		mc := RunMonteCarloSeeded(strategy, 10_000, 100, 58)
+		if math.Abs(mc.RTP-tt.want) > 0.4 {
+			t.Errorf("%s monte carlo RTP = %.2f%%, want about %.2f%%", tt.wheel, mc.RTP, tt.want)
+		}
+	}
+}
+
+func TestTakeProfitMultiple(t *testing.T) {
+	result := replay(t, "bankroll: 20\ntake_profit_multiple: 2\nbet: red, 0, 10\n", 1, 2, 1, 1, 1)
+	if result.SpinsPlayed != 4 || result.Outcome != ReachedTakeProfit || result.FinalBankroll != 40 {
+		t.Errorf("played %d spins stopping for %v at %v, want 4 stopping for %v at 40",
+			result.SpinsPlayed, result.Outcome, result.FinalBankroll, ReachedTakeProfit)
+	}
+
+	result = replay(t, "bankroll: 100\ntake_profit_multiple: 2\nbet: red, 0, 10\n", 1, 1, 2, 1)
+	if result.SpinsPlayed != 4 || result.Outcome != CompletedAllSpins {
+		t.Errorf("played %d spins stopping for %v, want all 4", result.SpinsPlayed, result.Outcome)
+	}
+
+	strategy, err := ParseStrategy("bankroll: 150\ntake_profit_multiple: 1.5\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if takeProfit, ok := strategy.takeProfit(); !ok || takeProfit != 225 || strategy.TakeProfitMultiple != 1.5 {
+		t.Errorf("take profit = %v from a multiple of %v, want 225 from 1.5", takeProfit, strategy.TakeProfitMultiple)
+	}
+}
+
+func TestOutcomes(t *testing.T) {
+	tests := []struct {
+		text  string
+		spins []int
+		want  Outcome
+	}{
+		{"bankroll: 100\nbet: red, 0, 10\n", []int{1, 2}, CompletedAllSpins},
+		{"bankroll: 100\ntake_profit: 110\nbet: red, 0, 10\n", []int{1, 2}, ReachedTakeProfit},
+		{"bankroll: 100\nstop_loss: 90\nbet: red, 0, 10\n", []int{2, 1}, HitStopLoss},
+		{"bankroll: 100\nmax_loss_streak: 1\nbet: red, 0, 10\n", []int{2, 1}, HitLossStreakLimit},
+		{"bankroll: 10\nbust_policy: stop\nbet: red, 0, 10\n", []int{2, 1}, WentBust},
+		// The take-profit reached on the last spin still counts
+		{"bankroll: 100\ntake_profit: 120\nbet: red, 0, 10\n", []int{1, 1}, ReachedTakeProfit},
+	}
+	for _, tt := range tests {
+		if got := replay(t, tt.text, tt.spins...).Outcome; got != tt.want {
+			t.Errorf("%q on %v ended %v, want %v", tt.text, tt.spins, got, tt.want)
+		}
+	}
+
+	counts := RunMonteCarloSeeded(mustParse(t, "bankroll: 50\nstop_loss: 20\ntake_profit: 80\nbet: red, 0, 10\n"), 100, 200, 1).Outcomes
+	if counts[HitStopLoss]+counts[ReachedTakeProfit]+counts[CompletedAllSpins] != 200 || counts[HitStopLoss] == 0 || counts[ReachedTakeProfit] == 0 {
+		t.Errorf("outcome counts = %v, want 200 runs split between stop-loss and take-profit", counts)
+	}
+
+	for o := CompletedAllSpins; o <= Interrupted; o++ {
+		text, err := o.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Outcome
+		if err := back.UnmarshalText(text); err != nil || back != o {
+			t.Errorf("%v came back as %v, %v", o, back, err)
+		}
+	}
+	var o Outcome
+	if err := o.UnmarshalText([]byte("lucky")); err == nil {
+		t.Error("an unknown outcome was read")
+	}
+}
+
+// mustParse parses a strategy, failing the test if it doesn't parse
+func mustParse(t *testing.T, text string) *Strategy {
+	t.Helper()
+	strategy, err := ParseStrategy(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strategy
+}
+
+func TestTwoWheels(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\nwheels: 2\nbet: red, 0, 10\n")
+	result, err := ReplayWithOptions(strategy, []int{1, 3, 1, 2, 2, 4}, SimulationOptions{RecordSpins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SpinsPlayed != 3 {
+		t.Fatalf("played %d rounds, want 3", result.SpinsPlayed)
+	}
+	for i, want := range []float64{20, 0, -20} {
+		record := result.SpinLog[i]
+		if record.NetChange != want || len(record.WinningNumbers) != 2 || record.Stakes[0] != 20 {
+			t.Errorf("round %d: numbers %v, staked %v, net %v; want 2 numbers, 20 staked and %v net",
+				i+1, record.WinningNumbers, record.Stakes, record.NetChange, want)
+		}
+	}
+	if result.FinalBankroll != 100 || result.BetsWon != 3 || result.BetsLost != 3 {
+		t.Errorf("final bankroll %v, won %d, lost %d; want 100, 3, 3", result.FinalBankroll, result.BetsWon, result.BetsLost)
+	}
+	if _, err := SimulateWithSpins(strategy, []int{1, 2, 3}); err == nil {
+		t.Error("3 spins were split between 2 wheels")
+	}
+
+	seeded := SimulateWithOptions(strategy, SimulationOptions{NumGames: 200, Seed: 65, RecordSpins: true})
+	same := 0
+	for _, record := range seeded.SpinLog {
+		if record.WinningNumbers[0] == record.WinningNumbers[1] {
+			same++
+		}
+	}
+	if same > 20 {
+		t.Errorf("the wheels agreed on %d of 200 spins, so they don't have their own streams", same)
+	}
+}
+
+func TestPocketCounts(t *testing.T) {
+	for _, tt := range []struct {
+		wheel   string
+		pockets int
+	}{{"american", 38}, {"european", 37}} {
+		strategy := mustParse(t, "bankroll: 100000\nwheel: "+tt.wheel+"\nbet: red, 0, 1\n")
+		result := SimulateWithOptions(strategy, SimulationOptions{NumGames: 20000, Seed: 69, CountPockets: true})
+		if len(result.PocketCounts) != tt.pockets {
+			t.Errorf("%s: %d pockets were hit, want all %d", tt.wheel, len(result.PocketCounts), tt.pockets)
+		}
+		total := 0
+		for pocket, count := range result.PocketCounts {
+			total += count
+			if !contains(NewWheel(strategy.Wheel).Numbers, pocket) {
+				t.Errorf("%s: pocket %s isn't on the wheel", tt.wheel, PocketLabel(pocket))
+			}
+		}
+		if total != result.SpinsPlayed {
+			t.Errorf("%s: counts add up to %d, want %d", tt.wheel, total, result.SpinsPlayed)
+		}
+	}
+	american := SimulateWithOptions(mustParse(t, "bankroll: 100000\nbet: red, 0, 1\n"), SimulationOptions{NumGames: 20000, Seed: 69, CountPockets: true})
+	if american.PocketCounts[0] == 0 || american.PocketCounts[DoubleZero] == 0 {
+		t.Errorf("0 came up %d times and 00 %d times", american.PocketCounts[0], american.PocketCounts[DoubleZero])
+	}
+	if quiet := SimulateSeeded(mustParse(t, "bankroll: 100\nbet: red, 0, 1\n"), 100, 1); quiet.PocketCounts != nil {
+		t.Error("pockets counted without CountPockets")
+	}
+}
+
+func TestReportedSeedReproducesRun(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 500\nprogression: fibonacci\nbet: red, 0, 5\nbet: number, 7, 1\n")
+	first := SimulateRoulette(strategy, 300)
+	if first.Seed == 0 {
+		t.Fatal("no seed was reported")
+	}
+	if again := SimulateSeeded(strategy, 300, first.Seed); !reflect.DeepEqual(first, again) {
+		t.Errorf("replaying seed %d gave %+v, want %+v", first.Seed, again, first)
+	}
+
+	mc := RunMonteCarlo(strategy, 100, 50)
+	if again := RunMonteCarloSeeded(strategy, 100, 50, mc.Seed); !reflect.DeepEqual(mc, again) {
+		t.Errorf("replaying monte carlo seed %d gave a different result", mc.Seed)
+	}
+}
+
+func TestSkippedBets(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 30\nbet: red, 0, 10\nbet: number, 17, 10\nbet: dozen, 3, 15\n")
+	result, err := ReplayWithOptions(strategy, []int{2, 2, 2}, SimulationOptions{RecordSpins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BetsSkipped != 6 {
+		t.Errorf("skipped %d bets, want 6", result.BetsSkipped)
+	}
+	for i, want := range [][]int{{2}, {1, 2}, {0, 1, 2}} {
+		if got := result.SpinLog[i].Skipped; !reflect.DeepEqual(got, want) {
+			t.Errorf("spin %d skipped %v, want %v", i+1, got, want)
+		}
+	}
+	for j, want := range []int{1, 2, 3} {
+		if got := result.PerBet[j].TimesSkipped; got != want {
+			t.Errorf("bet %d skipped %d times, want %d", j, got, want)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	for _, tt := range []struct {
+		cents int64
+		want  string
+	}{
+		{0, "$0.00"},
+		{5, "$0.05"},
+		{99999, "$999.99"},
+		{123456, "$1,234.56"},
+		{100000000, "$1,000,000.00"},
+		{123456789012, "$1,234,567,890.12"},
+		{-5000, "-$50.00"},
+		{-123456789, "-$1,234,567.89"},
+	} {
+		if got := FormatMoney(tt.cents); got != tt.want {
+			t.Errorf("FormatMoney(%d) = %q, want %q", tt.cents, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyFormats(t *testing.T) {
+	for _, tt := range []struct {
+		format MoneyFormat
+		cents  int64
+		want   string
+	}{
+		{MoneyFormat{Symbol: "€", Places: 2}, 123456, "€1,234.56"},
+		{MoneyFormat{Symbol: "£", Places: 1}, 123456, "£1,234.6"},
+		{MoneyFormat{Symbol: "£", Places: 1}, -123444, "-£1,234.4"},
+		{MoneyFormat{Symbol: "units", Places: 0}, 150, "2 units"},
+		{MoneyFormat{Symbol: "units", Places: 0}, -150, "-2 units"},
+		{MoneyFormat{Symbol: "units", Places: 0}, 149, "1 units"},
+		{MoneyFormat{Symbol: "EUR", Places: 2}, 99999950, "999,999.50 EUR"},
+		// Rounding to nothing drops the minus sign
+		{MoneyFormat{Symbol: "$", Places: 0}, -40, "$0"},
+	} {
+		if got := tt.format.Format(tt.cents); got != tt.want {
+			t.Errorf("%+v.Format(%d) = %q, want %q", tt.format, tt.cents, got, tt.want)
+		}
+	}
+	if got := (MoneyFormat{Symbol: "units", Places: 0}).FormatFine(-0.526); got != "-0.53 units" {
+		t.Errorf("FormatFine(-0.526) = %q, want %q", got, "-0.53 units")
+	}
+}
+
+func TestCurrencyAndPrecision(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\ncurrency: €\nprecision: 1\nbet: red, 0, 2.55\n")
+	if f := strategy.MoneyFormat(); f != (MoneyFormat{Symbol: "€", Places: 1}) {
+		t.Errorf("MoneyFormat() = %+v, want € to 1 place", f)
+	}
+	if got := strategy.Bets[0].Format(strategy.MoneyFormat()); got != "red €2.6" {
+		t.Errorf("bet = %q, want %q", got, "red €2.6")
+	}
+	if f := mustParse(t, "bankroll: 100\nbet: red, 0, 1\n").MoneyFormat(); f != DefaultMoneyFormat {
+		t.Errorf("default MoneyFormat() = %+v, want %+v", f, DefaultMoneyFormat)
+	}
+
+	// The format only changes how amounts read, never the cents behind them
+	spins := []int{1, 2, 3, 4, 0}
+	formatted := replay(t, "bankroll: 100\ncurrency: units\nprecision: 0\nbet: red, 0, 2.55\n", spins...)
+	plain := replay(t, "bankroll: 100\nbet: red, 0, 2.55\n", spins...)
+	if !reflect.DeepEqual(formatted, plain) {
+		t.Errorf("with a currency the result is %+v, want %+v", formatted, plain)
+	}
+	if formatted.FinalBankroll != 97.45 {
+		t.Errorf("final bankroll = %v, want 97.45", formatted.FinalBankroll)
+	}
+
+	for _, input := range []string{"currency: \n", "precision: 3\n", "precision: -1\n", "precision: two\n"} {
+		if _, err := ParseStrategy("bankroll: 100\n" + input + "bet: red, 0, 1\n"); err == nil {
+			t.Errorf("%q was accepted", input)
+		}
+	}
+}
+
+func TestOutcomePrecedence(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		spins []int
+		want  Outcome
+	}{
+		{"stop-loss over loss streak", "bankroll: 100\nstop_loss: 90\nmax_loss_streak: 1\nbet: red, 0, 10\n", []int{2, 1}, HitStopLoss},
+		{"stop-loss over bust", "bankroll: 10\nstop_loss: 5\nbust_policy: stop\nbet: red, 0, 10\n", []int{2, 1}, HitStopLoss},
+		{"loss streak over bust", "bankroll: 10\nmax_loss_streak: 1\nbust_policy: stop\nbet: red, 0, 10\n", []int{2, 1}, HitLossStreakLimit},
+		{"stop-loss over the last spin", "bankroll: 100\nstop_loss: 80\nbet: red, 0, 10\n", []int{1, 2, 2, 2}, HitStopLoss},
+		{"loss streak over the last spin", "bankroll: 100\nmax_loss_streak: 2\nbet: red, 0, 10\n", []int{1, 2, 2}, HitLossStreakLimit},
+		{"bust over the last spin", "bankroll: 10\nbust_policy: stop\nbet: red, 0, 10\n", []int{2}, WentBust},
+	}
+	for _, tt := range tests {
+		if got := replay(t, tt.text, tt.spins...).Outcome; got != tt.want {
+			t.Errorf("%s: ended %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Both ends of the range are met before the first spin, and the
+	// stop-loss wins
+	strategy := mustParse(t, "bankroll: 100\nbet: red, 0, 10\n")
+	stopLoss, takeProfit := 200.0, 50.0
+	strategy.StopLoss, strategy.TakeProfit = &stopLoss, &takeProfit
+	result, err := SimulateWithSpins(strategy, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Outcome != HitStopLoss || result.SpinsPlayed != 0 {
+		t.Errorf("stop-loss and take-profit together ended %v after %d spins, want %v after none", result.Outcome, result.SpinsPlayed, HitStopLoss)
+	}
+}
+
+func TestSpinLimitOnlyWhenNothingElseApplies(t *testing.T) {
+	// The take-profit is reached on the last spin MaxSpins allows
+	strategy := mustParse(t, "bankroll: 100\ntake_profit: 120\nbet: red, 0, 10\n")
+	result, err := ReplayWithOptions(strategy, []int{1, 1, 1, 1}, SimulationOptions{MaxSpins: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SpinsPlayed != 2 || result.Outcome != ReachedTakeProfit {
+		t.Errorf("played %d spins ending %v, want 2 ending %v", result.SpinsPlayed, result.Outcome, ReachedTakeProfit)
+	}
+
+	strategy = mustParse(t, "bankroll: 100\ntake_profit: 150\nbet: red, 0, 10\n")
+	result, err = ReplayWithOptions(strategy, []int{1, 1, 1, 1}, SimulationOptions{MaxSpins: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SpinsPlayed != 2 || result.Outcome != HitSpinLimit {
+		t.Errorf("played %d spins ending %v, want 2 ending %v", result.SpinsPlayed, result.Outcome, HitSpinLimit)
+	}
+
+	// Going bust on the last capped spin is still a bust
+	strategy = mustParse(t, "bankroll: 20\nbust_policy: stop\nbet: red, 0, 10\n")
+	result, err = ReplayWithOptions(strategy, []int{2, 2, 1, 1}, SimulationOptions{MaxSpins: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Outcome != WentBust {
+		t.Errorf("busting on the last capped spin ended %v, want %v", result.Outcome, WentBust)
+	}
+}
+
+// bettingSpins replays spins with the spin log on and returns the 1-based
+// spins bets were placed on, along with the result
+func bettingSpins(t *testing.T, text string, spins ...int) ([]int, *SimulationResult) {
+	t.Helper()
+	result, err := ReplayWithOptions(mustParse(t, text), spins, SimulationOptions{RecordSpins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var placed []int
+	for _, record := range result.SpinLog {
+		staked := false
+		for _, stake := range record.Stakes {
+			staked = staked || stake > 0
+		}
+		if staked == record.Spectated {
+			t.Errorf("spin %d staked %v but has Spectated %v", record.Spin, record.Stakes, record.Spectated)
+		}
+		if staked {
+			placed = append(placed, record.Spin)
+		}
+	}
+	return placed, result
+}
+
+func TestBetEvery(t *testing.T) {
+	spins := []int{2, 2, 2, 2, 2, 2, 2}
+	for _, tt := range []struct {
+		directive string
+		want      []int
+	}{
+		{"bet_every: 2", []int{2, 4, 6}},
+		{"bet_every: 2 1", []int{1, 3, 5, 7}},
+		{"bet_every: 3", []int{3, 6}},
+		{"bet_every: 3 2", []int{2, 5}},
+		{"bet_every: 1", []int{1, 2, 3, 4, 5, 6, 7}},
+	} {
+		placed, result := bettingSpins(t, "bankroll: 1000\n"+tt.directive+"\nbet: red, 0, 10\n", spins...)
+		if !reflect.DeepEqual(placed, tt.want) {
+			t.Errorf("%s bet on spins %v, want %v", tt.directive, placed, tt.want)
+		}
+		if result.SpinsPlayed != len(spins) || result.SpinsSpectated != len(spins)-len(tt.want) {
+			t.Errorf("%s played %d spins and watched %d, want %d and %d", tt.directive, result.SpinsPlayed, result.SpinsSpectated, len(spins), len(spins)-len(tt.want))
+		}
+		if want := 1000 - 10*float64(len(tt.want)); result.FinalBankroll != want {
+			t.Errorf("%s left %v, want %v", tt.directive, result.FinalBankroll, want)
+		}
+	}
+
+	for _, directive := range []string{"bet_every: 0", "bet_every: two", "bet_every: 2 3", "bet_every: 2 0", "bet_every:", "bet_every: 2 1 1"} {
+		if _, err := ParseStrategy("bankroll: 100\n" + directive + "\nbet: red, 0, 10\n"); err == nil {
+			t.Errorf("%q was accepted", directive)
+		}
+	}
+}
+
+func TestBetEveryWatchedSpins(t *testing.T) {
+	// The progression only moves on the spins that are bet
+	_, stakes := replayStakes(t, "bankroll: 1000\nbet_every: 2\nprogression: martingale\nbet: red, 0, 10\n", []int{1, 2, 1, 2, 2, 2})
+	if want := []float64{0, 10, 0, 20, 0, 40}; !reflect.DeepEqual(stakes, want) {
+		t.Errorf("stakes = %v, want %v", stakes, want)
+	}
+
+	// Watched spins still fill the adaptive window: spin 4 chases the 9s
+	// that came up on spins 2 and 3
+	got := adaptiveTargets(t, "bankroll: 1000\nbet_every: 2\nadaptive: hot\nwindow: 2\nbet: number, 17, 1\n", []int{5, 9, 9, 1})
+	if want := []int{-2, 5, 5, 9}; !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %v, want %v", got, want)
+	}
+}
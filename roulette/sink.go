@@ -0,0 +1,79 @@
+package roulette
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// spinSink streams spin records to SimulationOptions.SpinSink as they are
+// played. The first write error stops every write after it.
+type spinSink struct {
+	csv    *csv.Writer
+	json   *json.Encoder
+	buf    *bufio.Writer // Under json, which would otherwise write each record on its own
+	binary *binarySpinWriter
+	err    error
+}
+
+// newSpinSink returns a sink writing w in the given format: "csv", or empty,
+// for the same columns as WriteBankrollCSV, "json" for one JSON object per
+// line, or "binary" for the fixed-width records read by ReadSpinLogBinary,
+// sized for the given number of bets and wheels
+func newSpinSink(w io.Writer, format string, bets, wheels int) (*spinSink, error) {
+	switch format {
+	case "", "csv":
+		sink := &spinSink{csv: csv.NewWriter(w)}
+		sink.err = sink.csv.Write([]string{"spin", "winning_number", "bankroll"})
+		return sink, nil
+	case "json":
+		buf := bufio.NewWriter(w)
+		return &spinSink{json: json.NewEncoder(buf), buf: buf}, nil
+	case "binary":
+		sink := &spinSink{}
+		sink.binary, sink.err = newBinarySpinWriter(w, bets, wheels)
+		if sink.binary == nil {
+			return nil, sink.err
+		}
+		return sink, nil
+	}
+	return nil, fmt.Errorf("unknown spin format: %s", format)
+}
+
+// write streams one spin record
+func (s *spinSink) write(record SpinRecord) {
+	if s.err != nil {
+		return
+	}
+	if s.json != nil {
+		s.err = s.json.Encode(record)
+		return
+	}
+	if s.binary != nil {
+		s.err = s.binary.write(record)
+		return
+	}
+	s.err = s.csv.Write([]string{
+		strconv.Itoa(record.Spin),
+		PocketLabel(record.WinningNumber),
+		strconv.FormatFloat(record.Bankroll, 'f', -1, 64),
+	})
+}
+
+// flush writes out anything still buffered and returns the first error
+func (s *spinSink) flush() error {
+	if s.csv != nil && s.err == nil {
+		s.csv.Flush()
+		s.err = s.csv.Error()
+	}
+	if s.buf != nil && s.err == nil {
+		s.err = s.buf.Flush()
+	}
+	if s.binary != nil && s.err == nil {
+		s.err = s.binary.flush()
+	}
+	return s.err
+}
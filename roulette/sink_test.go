@@ -0,0 +1,114 @@
+package roulette
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSpinSinkStreamsEverySpin(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 1000000\nbet: red, 0, 1\n")
+	for _, format := range []string{"", "csv", "json"} {
+		var buf bytes.Buffer
+		result, err := SimulateWithOptionsContext(context.Background(), strategy, SimulationOptions{
+			NumGames:    5000,
+			Seed:        3,
+			RecordSpins: true,
+			SpinSink:    &buf,
+			SpinFormat:  format,
+		})
+		if err != nil {
+			t.Fatalf("format %q: %v", format, err)
+		}
+		if result.SpinLog != nil {
+			t.Errorf("format %q: result kept %d records as well as streaming them", format, len(result.SpinLog))
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		want := result.SpinsPlayed
+		if format != "json" {
+			want++ // Header row
+		}
+		if len(lines) != want {
+			t.Errorf("format %q: streamed %d lines, want %d for %d spins", format, len(lines), want, result.SpinsPlayed)
+		}
+		if format == "json" {
+			var last SpinRecord
+			if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+				t.Fatal(err)
+			}
+			if last.Spin != result.SpinsPlayed || last.Bankroll != result.FinalBankroll {
+				t.Errorf("last record is spin %d at %v, want spin %d at %v", last.Spin, last.Bankroll, result.SpinsPlayed, result.FinalBankroll)
+			}
+		}
+	}
+}
+
+func TestSpinSinkMatchesInMemoryLog(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\nbet: number, 17, 5\n")
+	spins := []int{17, 0, DoubleZero, 3, 17}
+	var buf bytes.Buffer
+	if _, err := ReplayWithOptions(strategy, spins, SimulationOptions{SpinSink: &buf, SpinFormat: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	logged, err := ReplayWithOptions(strategy, spins, SimulationOptions{RecordSpins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	enc := json.NewEncoder(&want)
+	for _, record := range logged.SpinLog {
+		enc.Encode(record)
+	}
+	if buf.String() != want.String() {
+		t.Errorf("streamed\n%s\nwant the in-memory log\n%s", buf.String(), want.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestSpinSinkErrors(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\nbet: red, 0, 1\n")
+	if _, err := SimulateWithOptionsContext(context.Background(), strategy, SimulationOptions{NumGames: 10, SpinSink: &bytes.Buffer{}, SpinFormat: "xml"}); err == nil {
+		t.Error("unknown spin format was accepted")
+	}
+	_, err := SimulateWithOptionsContext(context.Background(), strategy, SimulationOptions{NumGames: 10, SpinSink: failingWriter{}})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("got error %v, want the writer's error", err)
+	}
+}
+
+// countingWriter counts the writes made to it
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestSpinSinkBuffersJSON(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 1000000\nbet: red, 0, 1\n")
+	var w countingWriter
+	result, err := SimulateWithOptionsContext(context.Background(), strategy, SimulationOptions{NumGames: 5000, Seed: 3, SpinSink: &w, SpinFormat: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(w.String(), "\n"); lines != result.SpinsPlayed {
+		t.Errorf("streamed %d records, want %d", lines, result.SpinsPlayed)
+	}
+	if w.writes >= result.SpinsPlayed/10 {
+		t.Errorf("%d records took %d writes, want them buffered", result.SpinsPlayed, w.writes)
+	}
+
+	_, err = SimulateWithOptionsContext(context.Background(), strategy, SimulationOptions{NumGames: 10, SpinSink: failingWriter{}, SpinFormat: "json"})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("got error %v, want the writer's error from the final flush", err)
+	}
+}
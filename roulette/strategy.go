@@ -0,0 +1,869 @@
+package roulette
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Strategy represents a roulette betting strategy
+type Strategy struct {
+	Name               string // Label for the strategy, defaulting to its strategy: header
+	Description        string // Free-form notes on the strategy
+	InitialBankroll    float64
+	HouseBankroll      float64 // Money the casino starts with, zero when not tracked
+	Wheel              WheelType
+	Wheels             int            // Wheels played side by side from one bankroll, zero for one
+	Progression        string         // Name of the staking progression, empty for flat bets
+	Unit               float64        // D'Alembert step size, zero to use each bet's amount
+	LabouchereLine     []float64      // Starting Labouchere line in units of each bet's amount
+	ParoliSteps        int            // Wins in a row before Paroli resets, zero for the default
+	MartingaleMaxSteps int            // Losses in a row before Martingale resets, zero for no limit
+	StopLoss           *float64       // Stop once the bankroll falls to or below this, if set
+	TakeProfit         *float64       // Stop once the bankroll rises to or above this, if set
+	TakeProfitMultiple float64        // Stop once the bankroll reaches this multiple of InitialBankroll, zero if unset
+	MaxLossStreak      int            // Stop after this many losing spins in a row, zero for no limit
+	TableMin           float64        // Smallest stake the table accepts, zero for no minimum
+	TableMax           float64        // Largest stake the table accepts, zero for no maximum
+	LimitPolicy        string         // What to do when a stake exceeds TableMax: "cap" or "abandon"
+	BustPolicy         string         // What to do when no bet can be afforded: "skip" or "stop"
+	ZeroRule           string         // Even-money bets on 0: "none", "partage" or "prison"
+	Payouts            map[string]int // Net odds paid by bet type in place of the standard ones
+	Schedule           *Schedule      // Rotates which groups of bets play, nil to play them all
+	Phases             []Phase        // Stages played one after another, each with its own bets
+	Adaptive           string         // Move number bets to the "hot" or "cold" pocket of recent spins, empty to stay put
+	Window             int            // Recent spins adaptive betting looks at, zero for the default
+	BetEvery           int            // Bet only on every BetEvery-th spin, watching the rest, zero for every spin
+	BetFirst           int            // 1-based spin of each BetEvery the bet falls on, zero for the last, so bet_every: 2 bets the even spins
+	Trigger            *Trigger       // Condition the spins must meet before each episode of betting, nil to bet from the start
+	Strict             bool           // Fail Validate on problems that are otherwise only warnings
+	Currency           string         // Symbol money is written with, empty for "$"
+	Precision          *int           // Decimal places money is written to, nil for 2
+	Bets               []Bet
+}
+
+// String summarizes the strategy over several lines: its bankroll and wheel,
+// the rules that shape the session, and then its bets one per line
+func (s *Strategy) String() string {
+	var b strings.Builder
+	if s.Name != "" {
+		b.WriteString(s.Name + ": ")
+	}
+	f := s.MoneyFormat()
+	fmt.Fprintf(&b, "%s bankroll on the %s wheel", f.Format(ToCents(s.InitialBankroll)), s.Wheel)
+	if s.wheelCount() > 1 {
+		fmt.Fprintf(&b, " (%d wheels)", s.wheelCount())
+	}
+	b.WriteString("\n")
+	if s.Progression != "" {
+		fmt.Fprintf(&b, "  progression: %s\n", s.Progression)
+	}
+	if s.StopLoss != nil {
+		fmt.Fprintf(&b, "  stop loss: %s\n", f.Format(ToCents(*s.StopLoss)))
+	}
+	if s.Trigger != nil {
+		fmt.Fprintf(&b, "  trigger: %s\n", s.Trigger)
+	}
+	if takeProfit, ok := s.takeProfit(); ok {
+		fmt.Fprintf(&b, "  take profit: %s\n", f.Format(ToCents(takeProfit)))
+	}
+	for j, bet := range s.Bets {
+		fmt.Fprintf(&b, "  %d. %s", j+1, bet.Format(f))
+		if bet.Phase > 0 {
+			fmt.Fprintf(&b, " in phase %s", s.Phases[bet.Phase-1].Name)
+		}
+		if bet.Group != "" {
+			fmt.Fprintf(&b, " in group %s", bet.Group)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// takeProfit returns the bankroll that ends a run as a take-profit, if the
+// strategy has one. The multiple is resolved against InitialBankroll each
+// time, so a copy started from another bankroll keeps its target in
+// proportion. Given both a take-profit and a multiple, whichever is reached
+// first ends the run.
+func (s *Strategy) takeProfit() (float64, bool) {
+	if s.TakeProfitMultiple <= 0 {
+		if s.TakeProfit == nil {
+			return 0, false
+		}
+		return *s.TakeProfit, true
+	}
+	target := s.InitialBankroll * s.TakeProfitMultiple
+	if s.TakeProfit != nil && *s.TakeProfit < target {
+		target = *s.TakeProfit
+	}
+	return target, true
+}
+
+// betsOn reports whether bet_every has the strategy bet on the given 1-based
+// spin
+func (s *Strategy) betsOn(spin int) bool {
+	if s.BetEvery <= 1 {
+		return true
+	}
+	first := s.BetFirst
+	if first == 0 {
+		first = s.BetEvery
+	}
+	return spin%s.BetEvery == first%s.BetEvery
+}
+
+// MoneyFormat returns the format the strategy's amounts are written in, set
+// by its currency and precision directives
+func (s *Strategy) MoneyFormat() MoneyFormat {
+	f := DefaultMoneyFormat
+	if s.Currency != "" {
+		f.Symbol = s.Currency
+	}
+	if s.Precision != nil {
+		f.Places = *s.Precision
+	}
+	return f
+}
+
+// ParseStrategy parses the DSL input and returns a Strategy
+func ParseStrategy(input string) (*Strategy, error) {
+	return parseStrategyLines(strings.Split(input, "\n"), 1)
+}
+
+// ParseStrategies parses DSL input holding several strategies, each starting
+// with a "strategy: <name>" header, and returns them keyed by name
+func ParseStrategies(input string) (map[string]*Strategy, error) {
+	lines := strings.Split(input, "\n")
+	strategies := make(map[string]*Strategy)
+	name := ""
+	start := 0
+	flush := func(end int) error {
+		if name == "" {
+			return nil
+		}
+		strategy, err := parseStrategyLines(lines[start:end], start+1)
+		if err != nil {
+			return fmt.Errorf("strategy %s: %w", name, err)
+		}
+		if strategy.Name == "" {
+			strategy.Name = name
+		}
+		strategies[name] = strategy
+		return nil
+	}
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "strategy:") {
+			if name == "" && line != "" && !strings.HasPrefix(line, "#") {
+				return nil, &ParseError{Line: i + 1, Content: line, Reason: "expected a strategy: header first"}
+			}
+			continue
+		}
+		if err := flush(i); err != nil {
+			return nil, err
+		}
+		name = strings.TrimSpace(strings.TrimPrefix(line, "strategy:"))
+		if name == "" {
+			return nil, &ParseError{Line: i + 1, Content: line, Reason: "strategy name is missing"}
+		}
+		if _, ok := strategies[name]; ok {
+			return nil, &ParseError{Line: i + 1, Content: line, Reason: "duplicate strategy " + name}
+		}
+		start = i + 1
+	}
+	if err := flush(len(lines)); err != nil {
+		return nil, err
+	}
+	if len(strategies) == 0 {
+		return nil, fmt.Errorf("no strategy: headers found")
+	}
+	return strategies, nil
+}
+
+// HasStrategyHeaders reports whether input holds named strategy blocks that
+// should be read with ParseStrategies
+func HasStrategyHeaders(input string) bool {
+	for _, line := range strings.Split(input, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "strategy:") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseError describes a line of strategy DSL that couldn't be parsed
+type ParseError struct {
+	Line    int    // Line number in the input, counting from 1
+	Content string // The offending line, without surrounding whitespace
+	Reason  string // What is wrong with it
+}
+
+// Error reads like the messages of the rest of the parser, with the line
+// number and line after the reason
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s on line %d: %s", e.Reason, e.Line, e.Content)
+}
+
+// strategyParser holds what parsing one line of a strategy leaves behind for
+// the lines after it
+type strategyParser struct {
+	strategy *Strategy
+	group    string
+	phase    int // 1-based index of the phase being read, zero before any phase: line
+}
+
+// parseStrategyLines parses the DSL lines of a single strategy, numbering them
+// from firstLine in errors
+func parseStrategyLines(lines []string, firstLine int) (*Strategy, error) {
+	p := &strategyParser{strategy: &Strategy{}}
+	// The wheel is read up front, wherever its line is, so each bet can be
+	// checked against it as it is parsed
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "wheel:") {
+			if err := p.parseLine(line); err != nil {
+				return nil, &ParseError{Line: firstLine + i, Content: line, Reason: err.Error()}
+			}
+		}
+	}
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if err := p.parseLine(line); err != nil {
+			return nil, &ParseError{Line: firstLine + i, Content: line, Reason: err.Error()}
+		}
+	}
+	return p.finish()
+}
+
+// finish checks the strategy as a whole once every line has been parsed
+func (p *strategyParser) finish() (*Strategy, error) {
+	strategy := p.strategy
+
+	if strategy.TableMin > 0 && strategy.TableMax > 0 && strategy.TableMin > strategy.TableMax {
+		return nil, fmt.Errorf("table_min %v is above table_max %v", strategy.TableMin, strategy.TableMax)
+	}
+
+	// Formats that can't read the wheel first leave their bets to be
+	// checked here, along with the rules that depend on the wheel
+	if err := strategy.checkWheel(); err != nil {
+		return nil, err
+	}
+	if err := strategy.Schedule.validate(strategy.Bets); err != nil {
+		return nil, err
+	}
+	if err := strategy.checkPhases(); err != nil {
+		return nil, err
+	}
+	if strategy.Adaptive != "" && !strategy.hasBetType("number") {
+		return nil, fmt.Errorf("adaptive %s betting needs a number bet to move", strategy.Adaptive)
+	}
+
+	return strategy, nil
+}
+
+// checkWheel checks that the bets and rules of the strategy exist on its
+// wheel
+func (s *Strategy) checkWheel() error {
+	for _, bet := range s.Bets {
+		if err := s.checkBetWheel(bet); err != nil {
+			return err
+		}
+	}
+	if s.ZeroRule != "" && s.ZeroRule != "none" && s.Wheel != European {
+		return fmt.Errorf("zero_rule %s needs a european wheel, not %s", s.ZeroRule, s.Wheel)
+	}
+	return nil
+}
+
+// checkBetWheel checks that a bet can be placed on the strategy's wheel
+func (s *Strategy) checkBetWheel(bet Bet) error {
+	if bet.Type == "basket" && s.Wheel != American {
+		return fmt.Errorf("basket bets need an american wheel, not %s", s.Wheel)
+	}
+	if bet.Section != "" && s.Wheel != European {
+		return fmt.Errorf("section bets need a european wheel, not %s", s.Wheel)
+	}
+	if s.Wheel != American && ((bet.Type == "number" && bet.Value == DoubleZero) || contains(bet.Values, DoubleZero)) {
+		return fmt.Errorf("00 is not a pocket on the %s wheel", s.Wheel)
+	}
+	// On an American layout 00 sits above 3, so 0 only borders 1 and 2
+	if s.Wheel == American && bet.Type == "split" && contains(bet.Values, 0) && contains(bet.Values, 3) {
+		return fmt.Errorf("0 and 3 are not adjacent on an american table")
+	}
+	return nil
+}
+
+// parseLine parses a single trimmed line of a strategy
+func (p *strategyParser) parseLine(line string) error {
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil // Blank lines and comments
+	} else if strings.HasPrefix(line, "name:") {
+		p.strategy.Name = strings.TrimSpace(strings.TrimPrefix(line, "name:"))
+	} else if strings.HasPrefix(line, "description:") {
+		p.strategy.Description = strings.TrimSpace(strings.TrimPrefix(line, "description:"))
+	} else if strings.HasPrefix(line, "currency:") {
+		symbol := strings.TrimSpace(strings.TrimPrefix(line, "currency:"))
+		if symbol == "" {
+			return fmt.Errorf("currency needs a symbol")
+		}
+		p.strategy.Currency = symbol
+	} else if strings.HasPrefix(line, "precision:") {
+		places, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "precision:")))
+		if err != nil {
+			return fmt.Errorf("invalid precision: %v", err)
+		}
+		if places < 0 || places > 2 {
+			return fmt.Errorf("precision must be 0, 1 or 2 decimal places, got %d", places)
+		}
+		p.strategy.Precision = &places
+	} else if strings.HasPrefix(line, "bankroll:") {
+		bankrollStr := strings.TrimPrefix(line, "bankroll:")
+		bankroll, err := parseAmount(strings.TrimSpace(bankrollStr))
+		if err != nil {
+			return fmt.Errorf("invalid bankroll: %v", err)
+		}
+		if bankroll <= 0 {
+			return fmt.Errorf("bankroll must be positive, got %v", bankroll)
+		}
+		p.strategy.InitialBankroll = bankroll
+	} else if strings.HasPrefix(line, "house_bankroll:") {
+		bankroll, err := parseAmount(strings.TrimSpace(strings.TrimPrefix(line, "house_bankroll:")))
+		if err != nil {
+			return fmt.Errorf("invalid house_bankroll: %v", err)
+		}
+		if bankroll <= 0 {
+			return fmt.Errorf("house_bankroll must be positive, got %v", bankroll)
+		}
+		p.strategy.HouseBankroll = bankroll
+	} else if strings.HasPrefix(line, "wheel:") {
+		wheelType, err := ParseWheelType(strings.TrimPrefix(line, "wheel:"))
+		if err != nil {
+			return err
+		}
+		p.strategy.Wheel = wheelType
+	} else if strings.HasPrefix(line, "wheels:") {
+		wheels, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "wheels:")))
+		if err != nil {
+			return fmt.Errorf("invalid wheels: %v", err)
+		}
+		if wheels < 1 {
+			return fmt.Errorf("wheels must be at least 1, got %d", wheels)
+		}
+		p.strategy.Wheels = wheels
+	} else if strings.HasPrefix(line, "progression:") {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "progression:")))
+		if !isProgression(name) {
+			return fmt.Errorf("unknown progression: %s", name)
+		}
+		if p.phase > 0 {
+			p.strategy.Phases[p.phase-1].Progression = name
+		} else {
+			p.strategy.Progression = name
+		}
+	} else if strings.HasPrefix(line, "unit:") {
+		unit, err := parseAmount(strings.TrimSpace(strings.TrimPrefix(line, "unit:")))
+		if err != nil {
+			return fmt.Errorf("invalid unit: %v", err)
+		}
+		p.strategy.Unit = unit
+	} else if strings.HasPrefix(line, "paroli_steps:") {
+		steps, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "paroli_steps:")))
+		if err != nil {
+			return fmt.Errorf("invalid paroli_steps: %v", err)
+		}
+		if steps < 1 {
+			return fmt.Errorf("paroli_steps must be at least 1, got %d", steps)
+		}
+		p.strategy.ParoliSteps = steps
+	} else if strings.HasPrefix(line, "martingale_max_steps:") {
+		steps, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "martingale_max_steps:")))
+		if err != nil {
+			return fmt.Errorf("invalid martingale_max_steps: %v", err)
+		}
+		if steps < 1 {
+			return fmt.Errorf("martingale_max_steps must be at least 1, got %d", steps)
+		}
+		p.strategy.MartingaleMaxSteps = steps
+	} else if strings.HasPrefix(line, "labouchere_line:") {
+		p.strategy.LabouchereLine = nil
+		for _, field := range strings.Fields(strings.TrimPrefix(line, "labouchere_line:")) {
+			n, err := parseAmount(field)
+			if err != nil {
+				return fmt.Errorf("invalid labouchere_line: %v", err)
+			}
+			if n <= 0 {
+				return fmt.Errorf("labouchere_line numbers must be positive, got %v", n)
+			}
+			p.strategy.LabouchereLine = append(p.strategy.LabouchereLine, n)
+		}
+		if len(p.strategy.LabouchereLine) == 0 {
+			return fmt.Errorf("labouchere_line needs at least one number")
+		}
+	} else if strings.HasPrefix(line, "stop_loss:") {
+		stopLoss, err := parseAmount(strings.TrimSpace(strings.TrimPrefix(line, "stop_loss:")))
+		if err != nil {
+			return fmt.Errorf("invalid stop_loss: %v", err)
+		}
+		p.strategy.StopLoss = &stopLoss
+	} else if strings.HasPrefix(line, "take_profit:") {
+		takeProfit, err := parseAmount(strings.TrimSpace(strings.TrimPrefix(line, "take_profit:")))
+		if err != nil {
+			return fmt.Errorf("invalid take_profit: %v", err)
+		}
+		p.strategy.TakeProfit = &takeProfit
+	} else if strings.HasPrefix(line, "take_profit_multiple:") {
+		multiple, err := parseAmount(strings.TrimSpace(strings.TrimPrefix(line, "take_profit_multiple:")))
+		if err != nil {
+			return fmt.Errorf("invalid take_profit_multiple: %v", err)
+		}
+		if multiple <= 1 {
+			return fmt.Errorf("take_profit_multiple must be greater than 1, got %v", multiple)
+		}
+		p.strategy.TakeProfitMultiple = multiple
+	} else if strings.HasPrefix(line, "max_loss_streak:") {
+		streak, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "max_loss_streak:")))
+		if err != nil {
+			return fmt.Errorf("invalid max_loss_streak: %v", err)
+		}
+		if streak < 1 {
+			return fmt.Errorf("max_loss_streak must be at least 1, got %d", streak)
+		}
+		p.strategy.MaxLossStreak = streak
+	} else if strings.HasPrefix(line, "table_min:") {
+		tableMin, err := parseAmount(strings.TrimSpace(strings.TrimPrefix(line, "table_min:")))
+		if err != nil {
+			return fmt.Errorf("invalid table_min: %v", err)
+		}
+		if tableMin <= 0 {
+			return fmt.Errorf("table_min must be positive, got %v", tableMin)
+		}
+		p.strategy.TableMin = tableMin
+	} else if strings.HasPrefix(line, "table_max:") {
+		tableMax, err := parseAmount(strings.TrimSpace(strings.TrimPrefix(line, "table_max:")))
+		if err != nil {
+			return fmt.Errorf("invalid table_max: %v", err)
+		}
+		if tableMax <= 0 {
+			return fmt.Errorf("table_max must be positive, got %v", tableMax)
+		}
+		p.strategy.TableMax = tableMax
+	} else if strings.HasPrefix(line, "limit_policy:") {
+		policy := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "limit_policy:")))
+		if policy != "cap" && policy != "abandon" {
+			return fmt.Errorf("unknown limit_policy: %s", policy)
+		}
+		p.strategy.LimitPolicy = policy
+	} else if strings.HasPrefix(line, "bust_policy:") {
+		policy := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "bust_policy:")))
+		if policy != "skip" && policy != "stop" {
+			return fmt.Errorf("unknown bust_policy: %s", policy)
+		}
+		p.strategy.BustPolicy = policy
+	} else if strings.HasPrefix(line, "zero_rule:") {
+		rule := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "zero_rule:")))
+		if rule != "none" && rule != "partage" && rule != "prison" {
+			return fmt.Errorf("unknown zero_rule: %s", rule)
+		}
+		p.strategy.ZeroRule = rule
+	} else if strings.HasPrefix(line, "payout_") {
+		directive, value, _ := strings.Cut(line, ":")
+		betType := strings.TrimPrefix(directive, "payout_")
+		if _, ok := payoutMultiples[betType]; !ok {
+			return fmt.Errorf("unknown directive: %s", directive)
+		}
+		odds, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", directive, err)
+		}
+		if odds < 1 {
+			return fmt.Errorf("%s must be positive, got %d", directive, odds)
+		}
+		if p.strategy.Payouts == nil {
+			p.strategy.Payouts = make(map[string]int)
+		}
+		p.strategy.Payouts[betType] = odds
+	} else if strings.HasPrefix(line, "adaptive:") {
+		mode := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "adaptive:")))
+		if mode != "hot" && mode != "cold" && mode != "none" {
+			return fmt.Errorf("unknown adaptive mode: %s", mode)
+		}
+		if mode == "none" {
+			mode = ""
+		}
+		p.strategy.Adaptive = mode
+	} else if strings.HasPrefix(line, "window:") {
+		window, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "window:")))
+		if err != nil {
+			return fmt.Errorf("invalid window: %v", err)
+		}
+		if window < 1 {
+			return fmt.Errorf("window must be at least 1, got %d", window)
+		}
+		p.strategy.Window = window
+	} else if strings.HasPrefix(line, "bet_every:") {
+		// "bet_every: N" bets on spins N, 2N, ..., and "bet_every: N first"
+		// on spins first, first+N, ..., so "bet_every: 2 1" bets the odd spins
+		fields := strings.Fields(strings.TrimPrefix(line, "bet_every:"))
+		if len(fields) < 1 || len(fields) > 2 {
+			return fmt.Errorf("bet_every needs a count and optionally the first spin to bet")
+		}
+		every, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid bet_every: %v", err)
+		}
+		if every < 1 {
+			return fmt.Errorf("bet_every must be at least 1, got %d", every)
+		}
+		first := 0
+		if len(fields) == 2 {
+			if first, err = strconv.Atoi(fields[1]); err != nil {
+				return fmt.Errorf("invalid bet_every first spin: %v", err)
+			}
+			if first < 1 || first > every {
+				return fmt.Errorf("bet_every first spin must be from 1 to %d, got %d", every, first)
+			}
+		}
+		p.strategy.BetEvery, p.strategy.BetFirst = every, first
+	} else if strings.HasPrefix(line, "trigger:") {
+		trigger, err := parseTrigger(strings.TrimPrefix(line, "trigger:"))
+		if err != nil {
+			return err
+		}
+		p.strategy.Trigger = trigger
+	} else if strings.HasPrefix(line, "schedule:") {
+		groups := strings.Fields(strings.TrimPrefix(line, "schedule:"))
+		if len(groups) == 0 {
+			return fmt.Errorf("schedule needs at least one group")
+		}
+		p.strategy.Schedule = &Schedule{Groups: groups}
+	} else if strings.HasPrefix(line, "phase:") {
+		name := strings.TrimSpace(strings.TrimPrefix(line, "phase:"))
+		if name == "" {
+			return fmt.Errorf("phase name is missing")
+		}
+		p.strategy.Phases = append(p.strategy.Phases, Phase{Name: name})
+		p.phase = len(p.strategy.Phases)
+	} else if strings.HasPrefix(line, "until:") {
+		if p.phase == 0 {
+			return fmt.Errorf("until must follow a phase: line")
+		}
+		if err := p.strategy.Phases[p.phase-1].parseUntil(strings.TrimPrefix(line, "until:")); err != nil {
+			return err
+		}
+	} else if strings.HasPrefix(line, "group:") {
+		p.group = strings.TrimSpace(strings.TrimPrefix(line, "group:"))
+	} else if strings.HasPrefix(line, "bet:") {
+		bets, err := parseBet(strings.TrimPrefix(line, "bet:"))
+		if err != nil {
+			return err
+		}
+		for _, bet := range bets {
+			if err := p.strategy.checkBetWheel(bet); err != nil {
+				return err
+			}
+			bet.Group = p.group
+			bet.Phase = p.phase
+			p.strategy.Bets = append(p.strategy.Bets, bet)
+		}
+	} else {
+		directive, _, _ := strings.Cut(line, ":")
+		return fmt.Errorf("unknown directive: %s", strings.TrimSpace(directive))
+	}
+	return nil
+}
+
+// ParseStrategyFile reads a strategy written in the DSL from a file
+func ParseStrategyFile(path string) (*Strategy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	strategy, err := ParseStrategy(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return strategy, nil
+}
+
+// Phase is one stage of a compound strategy, such as flat bets until a small
+// profit is made and then a progression for the rest of the session. Phases
+// are played in order, each moving on to the next once its until condition
+// is met; the last one plays out the rest of the run.
+type Phase struct {
+	Name        string
+	Progression string  // Progression of the phase's bets, empty for the strategy's own
+	UntilProfit float64 // Move on once the phase has made this much, if positive
+	UntilSpins  int     // Move on after this many spins of the phase, if positive
+}
+
+// parseUntil parses the condition of an until: line, "profit <amount>" or
+// "spins <count>". A phase may have one of each, and moves on at whichever
+// comes first.
+func (ph *Phase) parseUntil(condition string) error {
+	fields := strings.Fields(condition)
+	if len(fields) != 2 {
+		return fmt.Errorf("until needs a condition: profit <amount> or spins <count>")
+	}
+	switch fields[0] {
+	case "profit":
+		profit, err := parseAmount(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid until profit: %v", err)
+		}
+		if profit <= 0 {
+			return fmt.Errorf("until profit must be positive, got %v", profit)
+		}
+		ph.UntilProfit = profit
+	case "spins":
+		spins, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid until spins: %v", err)
+		}
+		if spins < 1 {
+			return fmt.Errorf("until spins must be at least 1, got %d", spins)
+		}
+		ph.UntilSpins = spins
+	default:
+		return fmt.Errorf("unknown until condition: %s", fields[0])
+	}
+	return nil
+}
+
+// checkPhases checks that every phase has bets and every phase but the last
+// has a way to end, so each one can be reached
+func (s *Strategy) checkPhases() error {
+	for i, phase := range s.Phases {
+		if !s.hasPhaseBets(i + 1) {
+			return fmt.Errorf("phase %s has no bets", phase.Name)
+		}
+		if i < len(s.Phases)-1 && phase.UntilProfit <= 0 && phase.UntilSpins <= 0 {
+			return fmt.Errorf("phase %s has no until condition, so the phases after it never start", phase.Name)
+		}
+	}
+	return nil
+}
+
+// hasPhaseBets reports whether any bet belongs to the given 1-based phase
+func (s *Strategy) hasPhaseBets(phase int) bool {
+	for _, bet := range s.Bets {
+		if bet.Phase == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// firstPhase returns the phase a run starts in
+func (s *Strategy) firstPhase() int {
+	if len(s.Phases) == 0 {
+		return 0
+	}
+	return 1
+}
+
+// progressionFor returns the name of the progression bet j stakes with
+func (s *Strategy) progressionFor(j int) string {
+	if phase := s.Bets[j].Phase; phase > 0 && s.Phases[phase-1].Progression != "" {
+		return s.Phases[phase-1].Progression
+	}
+	return s.Progression
+}
+
+// inPhase reports whether a bet plays during the given 1-based phase. A
+// strategy without phases is in phase zero, where every bet plays.
+func (bet Bet) inPhase(phase int) bool {
+	return bet.Phase == 0 || bet.Phase == phase
+}
+
+// Schedule rotates through groups of bets, playing only the bets of one group
+// on each spin. Bets outside any group play every spin.
+type Schedule struct {
+	Groups []string // Group played on each spin in turn, repeating from the start
+}
+
+// Active reports whether bets in group play on the given 0-based spin
+func (s *Schedule) Active(group string, spin int) bool {
+	if s == nil || group == "" {
+		return true
+	}
+	return s.Groups[spin%len(s.Groups)] == group
+}
+
+// validate checks that every scheduled group has bets and every grouped bet
+// is scheduled
+func (s *Schedule) validate(bets []Bet) error {
+	grouped := make(map[string]bool)
+	for _, bet := range bets {
+		if bet.Group != "" {
+			grouped[bet.Group] = true
+		}
+	}
+	if s == nil {
+		if len(grouped) > 0 {
+			return fmt.Errorf("bets are grouped but there is no schedule")
+		}
+		return nil
+	}
+	scheduled := make(map[string]bool)
+	for _, group := range s.Groups {
+		if !grouped[group] {
+			return fmt.Errorf("scheduled group %s has no bets", group)
+		}
+		scheduled[group] = true
+	}
+	for group := range grouped {
+		if !scheduled[group] {
+			return fmt.Errorf("group %s isn't in the schedule", group)
+		}
+	}
+	return nil
+}
+
+// initialStake returns what a bet stakes on the first spin, resolving
+// percentage bets against the initial bankroll
+func (s *Strategy) initialStake(bet Bet) float64 {
+	if bet.Percent > 0 {
+		return s.InitialBankroll * bet.Percent / 100
+	}
+	return bet.Amount
+}
+
+// hasBetType reports whether any of the strategy's bets is of the given type
+func (s *Strategy) hasBetType(betType string) bool {
+	for _, bet := range s.Bets {
+		if bet.Type == betType {
+			return true
+		}
+	}
+	return false
+}
+
+// adaptiveWindow returns how many recent spins adaptive betting looks at
+func (s *Strategy) adaptiveWindow() int {
+	if s.Window < 1 {
+		return defaultAdaptiveWindow
+	}
+	return s.Window
+}
+
+// wheelCount returns how many wheels the strategy plays at once
+func (s *Strategy) wheelCount() int {
+	if s.Wheels < 1 {
+		return 1
+	}
+	return s.Wheels
+}
+
+// Validate checks that the strategy is ready to simulate. Problems that a
+// single line can show are caught while parsing, so this covers the
+// strategy as a whole.
+func (s *Strategy) Validate() error {
+	if len(s.Bets) == 0 {
+		return fmt.Errorf("strategy has no bets")
+	}
+	if s.Strict {
+		if err := s.checkFirstRound(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FirstRoundStake returns the total staked on the first round, a spin of
+// every wheel, with percentage bets taken from the initial bankroll
+func (s *Strategy) FirstRoundStake() float64 {
+	total := 0.0
+	for _, bet := range s.Bets {
+		if bet.inPhase(s.firstPhase()) && s.Schedule.Active(bet.Group, 0) {
+			total += s.initialStake(bet)
+		}
+	}
+	return total * float64(s.wheelCount())
+}
+
+// checkFirstRound reports a strategy whose first round already stakes more
+// than the initial bankroll, so some of its bets are skipped from the start
+func (s *Strategy) checkFirstRound() error {
+	if stake := s.FirstRoundStake(); stake > s.InitialBankroll {
+		f := s.MoneyFormat()
+		return fmt.Errorf("the first round stakes %s, more than the %s bankroll", f.Format(ToCents(stake)), f.Format(ToCents(s.InitialBankroll)))
+	}
+	return nil
+}
+
+// Warnings points out combinations of bets that hedge each other, covering
+// both sides of the table for equal amounts so they only pay the house edge,
+// and a first round that costs more than the bankroll. Validate turns the
+// latter into an error for a Strict strategy.
+func (s *Strategy) Warnings() []string {
+	totals := make(map[string]float64)
+	for _, bet := range s.Bets {
+		key := bet.Type
+		if bet.Type == "dozen" || bet.Type == "column" {
+			key = fmt.Sprintf("%s %d", bet.Type, bet.Value)
+		}
+		totals[key] += s.initialStake(bet)
+	}
+
+	var warnings []string
+	if err := s.checkFirstRound(); err != nil && !s.Strict {
+		warnings = append(warnings, err.Error())
+	}
+	for _, pair := range [][2]string{{"red", "black"}, {"odd", "even"}, {"low", "high"}} {
+		if totals[pair[0]] > 0 && totals[pair[0]] == totals[pair[1]] {
+			warnings = append(warnings, fmt.Sprintf("equal %s and %s bets cancel out, leaving only the house edge", pair[0], pair[1]))
+		}
+	}
+	for _, group := range []string{"dozen", "column"} {
+		first := totals[group+" 1"]
+		if first > 0 && totals[group+" 2"] == first && totals[group+" 3"] == first {
+			warnings = append(warnings, fmt.Sprintf("equal bets on every %s cancel out, leaving only the house edge", group))
+		}
+	}
+	return warnings
+}
+
+// ExpectedValuePerRound returns the exact expected net change in bankroll
+// from one round, a spin of every wheel, with every bet of the first phase
+// placed at its first-spin stake
+func (s *Strategy) ExpectedValuePerRound() float64 {
+	ev := 0.0
+	zeroProb := 1 / float64(len(NewWheel(s.Wheel).Numbers))
+	for _, bet := range s.Bets {
+		if !bet.inPhase(s.firstPhase()) {
+			continue
+		}
+		winProb := WinProbability(bet.Type, s.Wheel)
+		ret := s.PayoutMultiple(bet.Type) * winProb
+		if isEvenMoney(bet.Type) {
+			switch s.ZeroRule {
+			case "partage":
+				ret += zeroProb / 2
+			case "prison":
+				// The stake comes back if the bet wins the spin after the zero
+				ret += zeroProb * winProb
+			}
+		}
+		ev += s.initialStake(bet) * (ret - 1)
+	}
+	return ev * float64(s.wheelCount())
+}
+
+// Coverage returns how many distinct pockets of the given wheel at least one
+// of the strategy's bets wins on, and how many pockets the wheel has. Bets of
+// every phase and schedule group count, and adaptive number bets count at
+// the number they were written with.
+func (s *Strategy) Coverage(wheel WheelType) (covered int, total int) {
+	pockets := NewWheel(wheel).Numbers
+	for _, n := range pockets {
+		for _, bet := range s.Bets {
+			if betWins(bet, n) {
+				covered++
+				break
+			}
+		}
+	}
+	return covered, len(pockets)
+}
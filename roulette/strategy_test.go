@@ -0,0 +1,370 @@
+package roulette
+
+import (
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommentsAndBlankLines(t *testing.T) {
+	strategy, err := ParseStrategy("# a comment\n\nbankroll: 1000\n   \n  # indented comment\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strategy.InitialBankroll != 1000 || len(strategy.Bets) != 1 {
+		t.Errorf("got bankroll %v and %d bets, want 1000 and 1", strategy.InitialBankroll, len(strategy.Bets))
+	}
+
+	_, err = ParseStrategy("bankroll: 1000\nbett: even, 0, 5\n")
+	if err == nil || !strings.Contains(err.Error(), "unknown directive: bett") {
+		t.Errorf("got error %v, want unknown directive: bett", err)
+	}
+}
+
+func TestParseStrategyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "strategy.txt")
+	if err := os.WriteFile(path, []byte("bankroll: 500\nbet: black, 0, 5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	strategy, err := ParseStrategyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strategy.InitialBankroll != 500 || len(strategy.Bets) != 1 {
+		t.Errorf("got bankroll %v and %d bets, want 500 and 1", strategy.InitialBankroll, len(strategy.Bets))
+	}
+
+	bad := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(bad, []byte("bankroll: 500\nbet: black, 0, -5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseStrategyFile(bad)
+	if err == nil || !strings.HasPrefix(err.Error(), bad+": ") || !strings.Contains(err.Error(), "on line 2") {
+		t.Errorf("got error %v, want one naming %s and line 2", err, bad)
+	}
+
+	if _, err := ParseStrategyFile(filepath.Join(dir, "missing.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v for a missing file, want fs.ErrNotExist", err)
+	}
+}
+
+func TestExpectedValuePerRound(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"bankroll: 100\nbet: number, 17, 10\n", 10 * (36.0/38 - 1)},
+		{"bankroll: 100\nbet: red, 0, 10\n", 10 * (2*18.0/38 - 1)},
+		{"bankroll: 100\nwheel: european\nbet: number, 17, 10\n", 10 * (36.0/37 - 1)},
+		{"bankroll: 100\nwheel: european\nbet: even, 0, 10\n", 10 * (2*18.0/37 - 1)},
+		{"bankroll: 100\nbet: red, 0, 10\nbet: number, 17, 5\n", -10.0/19 - 5.0/19},
+	}
+	for _, tt := range tests {
+		strategy, err := ParseStrategy(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strategy.ExpectedValuePerRound(); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q: expected value %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	hedged, err := ParseStrategy("bankroll: 100\nbet: red, 0, 10\nbet: black, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := hedged.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "red") || !strings.Contains(warnings[0], "black") {
+		t.Errorf("warnings = %q, want one about red and black", warnings)
+	}
+
+	dozens, err := ParseStrategy("bankroll: 100\nbet: dozen, 1, 5\nbet: dozen, 2, 5\nbet: dozen, 3, 5\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dozens.Warnings()) == 0 {
+		t.Error("equal bets on all three dozens weren't flagged")
+	}
+
+	normal, err := ParseStrategy("bankroll: 100\nbet: red, 0, 10\nbet: odd, 0, 5\nbet: number, 17, 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warnings := normal.Warnings(); len(warnings) != 0 {
+		t.Errorf("warnings = %q, want none", warnings)
+	}
+}
+
+func TestParseStrategies(t *testing.T) {
+	input := "strategy: safe\nbankroll: 1000\nbet: red, 0, 5\n\nstrategy: wild\nbankroll: 200\nwheel: european\nbet: number, 7, 50\nbet: dozen, 2, 10\n"
+	if !HasStrategyHeaders(input) {
+		t.Error("headers not found")
+	}
+	strategies, err := ParseStrategies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(strategies) != 2 {
+		t.Fatalf("parsed %d strategies, want 2", len(strategies))
+	}
+	safe, wild := strategies["safe"], strategies["wild"]
+	if safe == nil || wild == nil {
+		t.Fatalf("strategies = %v, want safe and wild", strategies)
+	}
+	if safe.InitialBankroll != 1000 || safe.Wheel != American || len(safe.Bets) != 1 || safe.Bets[0].Type != "red" {
+		t.Errorf("safe = %+v", safe)
+	}
+	if wild.InitialBankroll != 200 || wild.Wheel != European || len(wild.Bets) != 2 || wild.Bets[0].Type != "number" {
+		t.Errorf("wild = %+v", wild)
+	}
+
+	_, err = ParseStrategies("strategy: broken\nbankroll: 100\nbet: number, 99, 5\n")
+	if err == nil || !strings.Contains(err.Error(), "strategy broken") || !strings.Contains(err.Error(), "on line 3") {
+		t.Errorf("got error %v, want one naming strategy broken and line 3", err)
+	}
+}
+
+func TestValidateNeedsBets(t *testing.T) {
+	empty, err := ParseStrategy("bankroll: 1000\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := empty.Validate(); err == nil || err.Error() != "strategy has no bets" {
+		t.Errorf("got error %v, want strategy has no bets", err)
+	}
+
+	normal, err := ParseStrategy("bankroll: 1000\nbet: red, 0, 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := normal.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestShorthandAmounts(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 1_000_000\nbet: red, 0, 1e3\nbet: number, 17, 2_500.50\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strategy.InitialBankroll != 1_000_000 {
+		t.Errorf("bankroll = %v, want 1000000", strategy.InitialBankroll)
+	}
+	if strategy.Bets[0].Amount != 1000 || strategy.Bets[1].Amount != 2500.50 {
+		t.Errorf("amounts = %v and %v, want 1000 and 2500.50", strategy.Bets[0].Amount, strategy.Bets[1].Amount)
+	}
+	if strategy, err := ParseStrategy("bankroll: 1e6\nbet: red, 0, 5\n"); err != nil || strategy.InitialBankroll != 1e6 {
+		t.Errorf("bankroll: 1e6 gave %v, %v", strategy, err)
+	}
+
+	for _, text := range []string{
+		"bankroll: NaN\nbet: red, 0, 5\n",
+		"bankroll: Inf\nbet: red, 0, 5\n",
+		"bankroll: 1e400\nbet: red, 0, 5\n",
+		"bankroll: 100\nbet: red, 0, NaN\n",
+		"bankroll: 100\nbet: red, 0, +Inf\n",
+		"bankroll: 1__000\nbet: red, 0, 5\n",
+	} {
+		if _, err := ParseStrategy(text); err == nil {
+			t.Errorf("%q was accepted", text)
+		}
+	}
+}
+
+func TestParseErrorLine(t *testing.T) {
+	_, err := ParseStrategy("# header\nbankroll: 100\n\n  bet: red, 0, ten  \n")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("got error %v, want a *ParseError", err)
+	}
+	if parseErr.Line != 4 || parseErr.Content != "bet: red, 0, ten" {
+		t.Errorf("line %d %q, want line 4 %q", parseErr.Line, parseErr.Content, "bet: red, 0, ten")
+	}
+	if parseErr.Reason == "" || !strings.HasPrefix(err.Error(), parseErr.Reason+" on line 4") {
+		t.Errorf("error %q doesn't lead with the reason %q", err, parseErr.Reason)
+	}
+
+	// Line numbers count from the top of the whole input, wrapped errors
+	// included
+	_, err = ParseStrategyFile(writeStrategyFile(t, "bankroll: 100\nbet: red, 0, 10\nbet: dozen, 4, 10\n"))
+	if !errors.As(err, &parseErr) || parseErr.Line != 3 {
+		t.Errorf("got error %v, want a *ParseError on line 3", err)
+	}
+	_, err = ParseStrategies("strategy: a\nbankroll: 100\nbet: red, 0, 10\nstrategy: b\nbankroll: 100\nbet: red, 0, ten\n")
+	if !errors.As(err, &parseErr) || parseErr.Line != 6 {
+		t.Errorf("got error %v, want a *ParseError on line 6", err)
+	}
+}
+
+// writeStrategyFile writes text to a strategy file in a fresh temporary
+// directory and returns its path
+func writeStrategyFile(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "strategy.txt")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFirstRoundOverBankroll(t *testing.T) {
+	// 60% of the 100 bankroll plus 50 more is 110 on the first round
+	over := mustParse(t, "bankroll: 100\nbet: red, 0, 60%\nbet: number, 17, 50\n")
+	if got := over.FirstRoundStake(); got != 110 {
+		t.Errorf("first round stakes %v, want 110", got)
+	}
+	if err := over.Validate(); err != nil {
+		t.Errorf("Validate without strict: %v", err)
+	}
+	warnings := over.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "the first round stakes $110.00, more than the $100.00 bankroll") {
+		t.Errorf("warnings = %q, want one about the first round", warnings)
+	}
+
+	over.Strict = true
+	if err := over.Validate(); err == nil || !strings.Contains(err.Error(), "the first round stakes $110.00") {
+		t.Errorf("strict Validate gave %v, want a first round error", err)
+	}
+	if len(over.Warnings()) != 0 {
+		t.Errorf("strict strategy still warns: %q", over.Warnings())
+	}
+
+	feasible := mustParse(t, "bankroll: 100\nbet: red, 0, 50%\nbet: number, 17, 50\n")
+	feasible.Strict = true
+	if err := feasible.Validate(); err != nil || len(feasible.Warnings()) != 0 {
+		t.Errorf("a first round of exactly the bankroll gave %v and %q", err, feasible.Warnings())
+	}
+}
+
+func TestDoubleZeroNeedsAmericanWheel(t *testing.T) {
+	for _, bet := range []string{"bet: number, 00, 5", "bet: split, 00 3, 5", "bet: basket, 0, 5", "bet: numbers, 0 00 17, 5"} {
+		if _, err := ParseStrategy("bankroll: 100\nwheel: american\n" + bet + "\n"); err != nil {
+			t.Errorf("%q on the american wheel: %v", bet, err)
+		}
+
+		// The wheel is read first, so the error points at the bet even when
+		// the wheel line comes after it
+		_, err := ParseStrategy("bankroll: 100\n" + bet + "\nwheel: european\n")
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line != 2 {
+			t.Errorf("%q on the european wheel: got error %v, want a *ParseError on line 2", bet, err)
+		} else if !strings.Contains(parseErr.Reason, "european") {
+			t.Errorf("%q on the european wheel: reason %q doesn't name the wheel", bet, parseErr.Reason)
+		}
+	}
+	if _, err := ParseStrategy("bankroll: 100\nwheel: european\nbet: number, 37, 5\n"); err == nil {
+		t.Error("number 37 was accepted")
+	}
+	if _, err := ParseStrategyYAML(strings.NewReader("bankroll: 100\nwheel: european\nbets:\n  - {type: number, value: 00, amount: 5}\n")); err == nil {
+		t.Error("YAML 00 bet on the european wheel was accepted")
+	}
+}
+
+// phaseStakes replays spins with the spin log on and returns every spin's
+// stakes along with the result
+func phaseStakes(t *testing.T, text string, spins ...int) (*SimulationResult, [][]float64) {
+	t.Helper()
+	result, err := ReplayWithOptions(mustParse(t, text), spins, SimulationOptions{RecordSpins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stakes := make([][]float64, len(result.SpinLog))
+	for i, record := range result.SpinLog {
+		stakes[i] = record.Stakes
+	}
+	return result, stakes
+}
+
+func TestPhaseMovesOnAtProfit(t *testing.T) {
+	// Flat red until 30 up, then a martingale on black
+	result, stakes := phaseStakes(t, "bankroll: 1000\nphase: flat\nbet: red, 0, 10\nuntil: profit 30\nphase: chase\nprogression: martingale\nbet: black, 0, 10\n",
+		1, 2, 3, 5, 7, 1, 2, 3)
+	want := [][]float64{{10, 0}, {10, 0}, {10, 0}, {10, 0}, {10, 0}, {0, 10}, {0, 20}, {0, 10}}
+	if !reflect.DeepEqual(stakes, want) {
+		t.Errorf("stakes = %v, want %v", stakes, want)
+	}
+	// Up 10, flat, 10, 20 and then 30 after the fifth spin
+	if !reflect.DeepEqual(result.PhaseStarts, []int{5}) {
+		t.Errorf("PhaseStarts = %v, want [5]", result.PhaseStarts)
+	}
+	if result.FinalBankroll != 1030 {
+		t.Errorf("final bankroll = %v, want 1030", result.FinalBankroll)
+	}
+}
+
+func TestPhaseMovesOnAfterSpins(t *testing.T) {
+	// Strategy-wide bets play in every phase
+	result, stakes := phaseStakes(t, "bankroll: 1000\nbet: number, 17, 1\nphase: a\nbet: red, 0, 5\nuntil: spins 2\nphase: b\nbet: black, 0, 5\nuntil: spins 1\nphase: c\nbet: even, 0, 5\n",
+		1, 1, 1, 1, 1)
+	want := [][]float64{{1, 5, 0, 0}, {1, 5, 0, 0}, {1, 0, 5, 0}, {1, 0, 0, 5}, {1, 0, 0, 5}}
+	if !reflect.DeepEqual(stakes, want) {
+		t.Errorf("stakes = %v, want %v", stakes, want)
+	}
+	if !reflect.DeepEqual(result.PhaseStarts, []int{2, 3}) {
+		t.Errorf("PhaseStarts = %v, want [2 3]", result.PhaseStarts)
+	}
+}
+
+func TestPhaseErrors(t *testing.T) {
+	for _, input := range []string{
+		"bankroll: 100\nuntil: profit 10\nbet: red, 0, 5\n",
+		"bankroll: 100\nphase: \nbet: red, 0, 5\n",
+		"bankroll: 100\nphase: a\nuntil: profit 10\nphase: b\nbet: red, 0, 5\n",
+		"bankroll: 100\nphase: a\nbet: red, 0, 5\nphase: b\nbet: black, 0, 5\n",
+		"bankroll: 100\nphase: a\nbet: red, 0, 5\nuntil: profit -5\nphase: b\nbet: black, 0, 5\n",
+		"bankroll: 100\nphase: a\nbet: red, 0, 5\nuntil: spins 0\nphase: b\nbet: black, 0, 5\n",
+		"bankroll: 100\nphase: a\nbet: red, 0, 5\nuntil: forever\nphase: b\nbet: black, 0, 5\n",
+	} {
+		if _, err := ParseStrategy(input); err == nil {
+			t.Errorf("%q was accepted", input)
+		}
+	}
+}
+
+func TestStrategyString(t *testing.T) {
+	strategy := mustParse(t, "name: demo\nbankroll: 1234.5\nwheel: american\nprogression: martingale\nstop_loss: 200\ntake_profit: 1500\nschedule: a\ngroup: a\nbet: even, 0, 10\ngroup:\nbet: number, 00, 2.5\nbet: dozen, 2, 5%\n")
+	want := `demo: $1,234.50 bankroll on the american wheel
+  progression: martingale
+  stop loss: $200.00
+  take profit: $1,500.00
+  1. even $10.00 in group a
+  2. number 00 $2.50
+  3. dozen 2 5% of bankroll
+`
+	if got := strategy.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	for _, tt := range []struct {
+		text           string
+		wheel          WheelType
+		covered, total int
+	}{
+		// Red and the green zero
+		{"bankroll: 100\nbet: red, 0, 10\nbet: number, 0, 1\n", American, 19, 38},
+		{"bankroll: 100\nbet: red, 0, 10\nbet: number, 0, 1\n", European, 19, 37},
+		// Overlaps count once
+		{"bankroll: 100\nbet: red, 0, 10\nbet: number, 1, 1\nbet: dozen, 1, 5\n", American, 24, 38},
+		// Every number but the greens, then the greens as well
+		{"bankroll: 100\nbet: dozen, 1, 5\nbet: dozen, 2, 5\nbet: dozen, 3, 5\n", American, 36, 38},
+		{"bankroll: 100\nbet: dozen, 1, 5\nbet: dozen, 2, 5\nbet: dozen, 3, 5\nbet: split, 0 00, 1\n", American, 38, 38},
+		{"bankroll: 100\nwheel: european\nbet: low, 0, 5\nbet: high, 0, 5\nbet: number, 0, 1\n", European, 37, 37},
+		// Phases and schedule groups all count
+		{"bankroll: 100\nphase: a\nbet: red, 0, 5\nuntil: spins 1\nphase: b\nbet: black, 0, 5\n", American, 36, 38},
+	} {
+		covered, total := mustParse(t, tt.text).Coverage(tt.wheel)
+		if covered != tt.covered || total != tt.total {
+			t.Errorf("%q on the %s wheel covers %d of %d, want %d of %d", tt.text, tt.wheel, covered, total, tt.covered, tt.total)
+		}
+	}
+}
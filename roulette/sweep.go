@@ -0,0 +1,88 @@
+package roulette
+
+// SweepPoint is how a strategy fared at one setting of a sweep
+type SweepPoint struct {
+	Size            float64 `json:"size"`             // Setting the strategy was run at
+	MeanProfit      float64 `json:"mean_profit"`      // Mean final bankroll less the one started with
+	RuinProbability float64 `json:"ruin_probability"` // Share of runs that went bust
+}
+
+// SweepBetSize runs a Monte Carlo simulation of the strategy at every base bet
+// size in sizes, returning a point for each one in the order given. The base
+// bet is the strategy's first fixed-amount bet; every other fixed-amount bet
+// and the D'Alembert unit are scaled by the same factor, so the bets keep
+// their proportions, while bets staking a percentage of the bankroll are left
+// alone. All sizes share one seed, so run i of each sees the same wheel. A
+// strategy without a fixed-amount bet has nothing to scale and returns nil.
+func SweepBetSize(strategy *Strategy, sizes []float64, numGames, numRuns int) []SweepPoint {
+	return SweepBetSizeSeeded(strategy, sizes, numGames, numRuns, newSeed())
+}
+
+// SweepBetSizeSeeded is like SweepBetSize but runs every size from seed, so
+// the sweep is reproducible
+func SweepBetSizeSeeded(strategy *Strategy, sizes []float64, numGames, numRuns int, seed int64) []SweepPoint {
+	base := 0.0
+	for _, bet := range strategy.Bets {
+		if bet.Percent == 0 {
+			base = bet.Amount
+			break
+		}
+	}
+	if base <= 0 {
+		return nil
+	}
+	points := make([]SweepPoint, 0, len(sizes))
+	for _, size := range sizes {
+		scaled := scaleBets(strategy, size/base)
+		mc := RunMonteCarloSeeded(scaled, numGames, numRuns, seed)
+		points = append(points, SweepPoint{
+			Size:            size,
+			MeanProfit:      mc.Mean - strategy.InitialBankroll,
+			RuinProbability: mc.RuinProbability(),
+		})
+	}
+	return points
+}
+
+// SweepBankroll runs a Monte Carlo simulation of the strategy from every
+// starting bankroll in bankrolls, returning a point for each one in the order
+// given, to show how much a player needs to bring to last numGames spins. The
+// bets are left as they are, and each point is run from a seed of its own so
+// no bankroll's chances lean on the same lucky or unlucky spins as another's.
+// A take_profit_multiple is taken of each starting bankroll in turn.
+func SweepBankroll(strategy *Strategy, bankrolls []float64, numGames, numRuns int) []SweepPoint {
+	return SweepBankrollSeeded(strategy, bankrolls, numGames, numRuns, newSeed())
+}
+
+// SweepBankrollSeeded is like SweepBankroll but derives every run's seed from
+// seed, so the sweep is reproducible. The i-th bankroll is run from
+// seed+i*numRuns, as CompareStrategiesSeeded does for its strategies.
+func SweepBankrollSeeded(strategy *Strategy, bankrolls []float64, numGames, numRuns int, seed int64) []SweepPoint {
+	points := make([]SweepPoint, 0, len(bankrolls))
+	for i, bankroll := range bankrolls {
+		funded := *strategy
+		funded.InitialBankroll = bankroll
+		mc := RunMonteCarloSeeded(&funded, numGames, numRuns, seed+int64(i)*int64(numRuns))
+		points = append(points, SweepPoint{
+			Size:            bankroll,
+			MeanProfit:      mc.Mean - bankroll,
+			RuinProbability: mc.RuinProbability(),
+		})
+	}
+	return points
+}
+
+// scaleBets returns a copy of the strategy with its fixed-amount bets and
+// D'Alembert unit multiplied by factor
+func scaleBets(strategy *Strategy, factor float64) *Strategy {
+	scaled := *strategy
+	scaled.Unit *= factor
+	scaled.Bets = make([]Bet, len(strategy.Bets))
+	for j, bet := range strategy.Bets {
+		if bet.Percent == 0 {
+			bet.Amount = FromCents(ToCents(bet.Amount * factor))
+		}
+		scaled.Bets[j] = bet
+	}
+	return &scaled
+}
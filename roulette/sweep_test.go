@@ -0,0 +1,133 @@
+package roulette
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSweepBetSizeRaisesRuin(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\nbet: red, 0, 10\n")
+	sizes := []float64{1, 5, 20, 50, 100}
+	points := SweepBetSize(strategy, sizes, 200, 2000)
+	if len(points) != len(sizes) {
+		t.Fatalf("got %d points, want %d", len(points), len(sizes))
+	}
+	for i, point := range points {
+		if point.Size != sizes[i] {
+			t.Errorf("point %d is for size %v, want %v", i, point.Size, sizes[i])
+		}
+		if i > 0 && point.RuinProbability <= points[i-1].RuinProbability {
+			t.Errorf("ruin at %v a spin is %v, not above %v at %v", point.Size, point.RuinProbability, points[i-1].RuinProbability, points[i-1].Size)
+		}
+	}
+	if points[0].RuinProbability != 0 {
+		t.Errorf("ruin betting 1 of 100 for 200 spins = %v, want 0", points[0].RuinProbability)
+	}
+	// Losses grow with the stakes, so bigger bets lose more on average
+	if points[1].MeanProfit >= points[0].MeanProfit {
+		t.Errorf("mean profit at 5 = %v, want a bigger loss than %v at 1", points[1].MeanProfit, points[0].MeanProfit)
+	}
+	// The strategy itself is left as it was
+	if strategy.Bets[0].Amount != 10 {
+		t.Errorf("sweeping changed the strategy's bet to %v", strategy.Bets[0].Amount)
+	}
+}
+
+func TestSweepBetSizeScalesFixedBets(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 1000\nprogression: dalembert\nunit: 2\nbet: dozen, 1, 5%\nbet: red, 0, 10\nbet: number, 17, 2.5\n")
+	scaled := scaleBets(strategy, 3)
+	if scaled.Unit != 6 {
+		t.Errorf("unit = %v, want 6", scaled.Unit)
+	}
+	amounts := []float64{scaled.Bets[0].Amount, scaled.Bets[1].Amount, scaled.Bets[2].Amount}
+	if !reflect.DeepEqual(amounts, []float64{0, 30, 7.5}) || scaled.Bets[0].Percent != 5 {
+		t.Errorf("scaled bets = %v at %v%%, want [0 30 7.5] at 5%%", amounts, scaled.Bets[0].Percent)
+	}
+	if strategy.Bets[1].Amount != 10 || strategy.Unit != 2 {
+		t.Error("scaling changed the original strategy")
+	}
+
+	if points := SweepBetSize(mustParse(t, "bankroll: 100\nbet: red, 0, 10%\n"), []float64{1, 2}, 10, 10); points != nil {
+		t.Errorf("percentage bets alone gave %v, want nil", points)
+	}
+}
+
+func TestSweepBetSizeSeededRepeats(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\nbet: red, 0, 10\n")
+	sizes := []float64{5, 10, 20}
+	first := SweepBetSizeSeeded(strategy, sizes, 100, 50, 92)
+	if again := SweepBetSizeSeeded(strategy, sizes, 100, 50, 92); !reflect.DeepEqual(first, again) {
+		t.Errorf("the same seed swept to %+v, then %+v", first, again)
+	}
+	if other := SweepBetSizeSeeded(strategy, sizes, 100, 50, 93); reflect.DeepEqual(first, other) {
+		t.Errorf("seeds 92 and 93 both swept to %+v", first)
+	}
+}
+
+func TestSweepBankrollLowersRuin(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\nbet: red, 0, 10\n")
+	bankrolls := []float64{10, 50, 200, 1000}
+	points := SweepBankroll(strategy, bankrolls, 200, 2000)
+	if len(points) != len(bankrolls) {
+		t.Fatalf("got %d points, want %d", len(points), len(bankrolls))
+	}
+	for i, point := range points {
+		if point.Size != bankrolls[i] {
+			t.Errorf("point %d is for bankroll %v, want %v", i, point.Size, bankrolls[i])
+		}
+		if i > 0 && point.RuinProbability >= points[i-1].RuinProbability && point.RuinProbability > 0 {
+			t.Errorf("ruin from %v is %v, not below %v from %v", point.Size, point.RuinProbability, points[i-1].RuinProbability, points[i-1].Size)
+		}
+	}
+	// A hundred bets can't all be lost in 200 spins at these odds
+	if last := points[len(points)-1]; last.RuinProbability != 0 {
+		t.Errorf("ruin from 1000 = %v, want 0", last.RuinProbability)
+	}
+	// Profit is measured from each point's own bankroll
+	if last := points[len(points)-1]; last.MeanProfit > 0 || last.MeanProfit < -200 {
+		t.Errorf("mean profit from 1000 = %v, want a small loss", last.MeanProfit)
+	}
+	if strategy.InitialBankroll != 100 {
+		t.Errorf("sweeping changed the strategy's bankroll to %v", strategy.InitialBankroll)
+	}
+}
+
+func TestSweepBankrollScalesTakeProfitMultiple(t *testing.T) {
+	// Doubling 100 ends a run at 200, but from 1000 the target is 2000, not
+	// the 200 the strategy's own bankroll works out to
+	strategy := mustParse(t, "bankroll: 100\ntake_profit_multiple: 2\nbet: red, 0, 10\n")
+	points := SweepBankrollSeeded(strategy, []float64{100, 1000}, 500, 200, 93)
+	if points[1].MeanProfit == 0 {
+		t.Error("every run from 1000 stopped before the first spin")
+	}
+	if over := points[1].MeanProfit; over > 1000 {
+		t.Errorf("mean profit from 1000 = %v, more than doubling it", over)
+	}
+	if points[0].MeanProfit > 100 {
+		t.Errorf("mean profit from 100 = %v, more than doubling it", points[0].MeanProfit)
+	}
+
+	// An absolute take_profit stays where it was, so from 1000 it has
+	// already been reached
+	capped := mustParse(t, "bankroll: 100\ntake_profit: 150\ntake_profit_multiple: 2\nbet: red, 0, 10\n")
+	if got := SweepBankrollSeeded(capped, []float64{1000}, 500, 20, 93)[0].MeanProfit; got != 0 {
+		t.Errorf("mean profit from 1000 with a take-profit of 150 = %v, want 0", got)
+	}
+}
+
+func TestSweepBankrollSeedsEachPoint(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 100\nbet: red, 0, 10\n")
+	bankrolls := []float64{50, 100}
+	points := SweepBankrollSeeded(strategy, bankrolls, 100, 50, 93)
+	if again := SweepBankrollSeeded(strategy, bankrolls, 100, 50, 93); !reflect.DeepEqual(points, again) {
+		t.Errorf("the same seed swept to %+v, then %+v", points, again)
+	}
+	for i, bankroll := range bankrolls {
+		funded := *strategy
+		funded.InitialBankroll = bankroll
+		mc := RunMonteCarloSeeded(&funded, 100, 50, 93+int64(i)*50)
+		if points[i].MeanProfit != mc.Mean-bankroll {
+			t.Errorf("bankroll %v swept to a mean profit of %v, want %v from seed %d", bankroll, points[i].MeanProfit, mc.Mean-bankroll, 93+i*50)
+		}
+	}
+}
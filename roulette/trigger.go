@@ -0,0 +1,50 @@
+package roulette
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Trigger holds a strategy's bets back until the spins have come up a certain
+// way, as in waiting for three reds in a row before betting black. Once it
+// fires, the bets play an episode that lasts until a spin ends with a net
+// win; the trigger then re-arms and waits for a fresh streak.
+type Trigger struct {
+	Type  string // Outside bet type the spins must land on: red, black, odd, even, low, high or green
+	Count int    // Spins in a row that must land on Type
+}
+
+// parseTrigger parses the condition of a trigger: line, "streak <type> <count>"
+func parseTrigger(condition string) (*Trigger, error) {
+	fields := strings.Fields(strings.ToLower(condition))
+	if len(fields) != 3 || fields[0] != "streak" {
+		return nil, fmt.Errorf("trigger needs a condition: streak <type> <count>")
+	}
+	switch fields[1] {
+	case "red", "black", "odd", "even", "low", "high", "green":
+	default:
+		return nil, fmt.Errorf("unknown trigger type %s: must be red, black, odd, even, low, high or green", fields[1])
+	}
+	count, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid trigger count: %v", err)
+	}
+	if count < 1 {
+		return nil, fmt.Errorf("trigger count must be at least 1, got %d", count)
+	}
+	return &Trigger{Type: fields[1], Count: count}, nil
+}
+
+// matches reports whether a winning number counts toward the trigger's streak
+func (t *Trigger) matches(winningNumber int) bool {
+	if t.Type == "green" {
+		return IsGreen(winningNumber)
+	}
+	return betWins(Bet{Type: t.Type}, winningNumber)
+}
+
+// String describes the trigger the way it is written in the DSL
+func (t *Trigger) String() string {
+	return fmt.Sprintf("streak %s %d", t.Type, t.Count)
+}
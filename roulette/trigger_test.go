@@ -0,0 +1,60 @@
+package roulette
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTriggerStartsBetting(t *testing.T) {
+	// Three reds in a row start an episode of black bets, which ends on a
+	// winning spin and waits for three more reds
+	spins := []int{1, 3, 2, 5, 7, 9, 12, 2, 14, 16, 18, 0, 4}
+	placed, result := bettingSpins(t, "bankroll: 1000\ntrigger: streak red 3\nbet: black, 0, 10\n", spins...)
+	if want := []int{7, 8, 12, 13}; !reflect.DeepEqual(placed, want) {
+		t.Errorf("bet on spins %v, want %v", placed, want)
+	}
+	if result.TriggersFired != 2 {
+		t.Errorf("TriggersFired = %d, want 2", result.TriggersFired)
+	}
+	if result.SpinsSpectated != len(spins)-4 || result.FinalBankroll != 1000 {
+		t.Errorf("watched %d spins and left %v, want %d and 1000", result.SpinsSpectated, result.FinalBankroll, len(spins)-4)
+	}
+
+	// A trigger that never fires never bets
+	placed, result = bettingSpins(t, "bankroll: 1000\ntrigger: streak green 2\nbet: number, 0, 10\n", 0, 1, 0, 2)
+	if placed != nil || result.TriggersFired != 0 || result.FinalBankroll != 1000 {
+		t.Errorf("bet on spins %v after %d triggers, leaving %v; want no bets", placed, result.TriggersFired, result.FinalBankroll)
+	}
+	placed, _ = bettingSpins(t, "bankroll: 1000\ntrigger: streak green 1\nbet: number, 0, 10\n", 1, DoubleZero, 2, 0, 0)
+	if want := []int{3, 4}; !reflect.DeepEqual(placed, want) {
+		t.Errorf("green trigger bet on spins %v, want %v", placed, want)
+	}
+}
+
+func TestTriggerEpisodeLastsUntilAWin(t *testing.T) {
+	// A martingale keeps doubling through the episode
+	_, stakes := replayStakes(t, "bankroll: 1000\ntrigger: streak odd 1\nprogression: martingale\nbet: even, 0, 10\n",
+		[]int{1, 3, 5, 7, 2, 4})
+	if want := []float64{0, 10, 20, 40, 80, 0}; !reflect.DeepEqual(stakes, want) {
+		t.Errorf("stakes = %v, want %v", stakes, want)
+	}
+}
+
+func TestParseTrigger(t *testing.T) {
+	trigger, err := parseTrigger(" Streak RED 3 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *trigger != (Trigger{Type: "red", Count: 3}) || trigger.String() != "streak red 3" {
+		t.Errorf("got %+v written %q, want red 3 written %q", *trigger, trigger, "streak red 3")
+	}
+	if strategy := mustParse(t, "bankroll: 100\ntrigger: streak high 2\nbet: low, 0, 5\n"); strategy.Trigger == nil || strategy.Trigger.String() != "streak high 2" {
+		t.Errorf("strategy trigger = %v, want streak high 2", strategy.Trigger)
+	}
+
+	for _, condition := range []string{"", "streak red", "run red 3", "streak purple 3", "streak red three", "streak red 0", "streak red 3 4"} {
+		if _, err := parseTrigger(condition); err == nil {
+			t.Errorf("%q was accepted", condition)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package roulette
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// VerifyRun plays numGames spins of the strategy from seed and checks that
+// the result matches expected field by field, so a saved result can serve as
+// a golden file or as proof of how a published strategy behaves. The spin log
+// and pocket counts are only compared when expected holds them. A mismatch
+// is reported as an error listing every field that differs.
+func VerifyRun(strategy *Strategy, seed int64, numGames int, expected *SimulationResult) error {
+	opts := SimulationOptions{
+		NumGames:     numGames,
+		Seed:         seed,
+		RecordSpins:  len(expected.SpinLog) > 0,
+		CountPockets: expected.PocketCounts != nil,
+	}
+	got := SimulateWithOptions(strategy, opts)
+
+	var diffs []string
+	gotValue, wantValue := reflect.ValueOf(got).Elem(), reflect.ValueOf(expected).Elem()
+	fields := gotValue.Type()
+	for i := 0; i < fields.NumField(); i++ {
+		g, w := gotValue.Field(i).Interface(), wantValue.Field(i).Interface()
+		if reflect.DeepEqual(g, w) {
+			continue
+		}
+		name, _, _ := strings.Cut(fields.Field(i).Tag.Get("json"), ",")
+		diffs = append(diffs, fmt.Sprintf("%s: got %s, want %s", name, describeValue(g), describeValue(w)))
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("run doesn't match the expected result:\n  %s", strings.Join(diffs, "\n  "))
+	}
+	return nil
+}
+
+// describeValue renders a result field for a VerifyRun diff. Fields are
+// written as JSON, so nested stats print their contents rather than pointers.
+func describeValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
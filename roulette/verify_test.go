@@ -0,0 +1,62 @@
+package roulette
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestVerifyRunMatches(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 500\nprogression: martingale\nbet: red, 0, 5\nbet: number, 17, 1\n")
+	expected := SimulateWithOptions(strategy, SimulationOptions{NumGames: 300, Seed: 21, RecordSpins: true, CountPockets: true})
+	if err := VerifyRun(strategy, 21, 300, expected); err != nil {
+		t.Errorf("a run didn't match itself: %v", err)
+	}
+
+	// A result saved as JSON verifies just the same
+	data, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved SimulationResult
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyRun(strategy, 21, 300, &saved); err != nil {
+		t.Errorf("the saved run didn't match: %v", err)
+	}
+
+	// Without a spin log or pocket counts there's nothing of them to compare
+	summary := SimulateSeeded(strategy, 300, 21)
+	if err := VerifyRun(strategy, 21, 300, summary); err != nil {
+		t.Errorf("a run without the spin log didn't match: %v", err)
+	}
+}
+
+func TestVerifyRunMismatch(t *testing.T) {
+	strategy := mustParse(t, "bankroll: 500\nbet: red, 0, 5\n")
+	expected := SimulateSeeded(strategy, 300, 21)
+	expected.FinalBankroll += 5
+	expected.BetsWon++
+	err := VerifyRun(strategy, 21, 300, expected)
+	if err == nil {
+		t.Fatal("a doctored result matched")
+	}
+	for _, want := range []string{"final_bankroll: got ", "bets_won: got "} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't report %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "bets_lost") || strings.Contains(err.Error(), "spins_played") {
+		t.Errorf("error %q reports fields that match", err)
+	}
+
+	// A different seed or length plays a different run
+	expected = SimulateSeeded(strategy, 300, 21)
+	if VerifyRun(strategy, 22, 300, expected) == nil {
+		t.Error("a run from another seed matched")
+	}
+	if VerifyRun(strategy, 21, 299, expected) == nil {
+		t.Error("a shorter run matched")
+	}
+}
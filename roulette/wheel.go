@@ -0,0 +1,130 @@
+package roulette
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EuropeanWheelOrder lists the pockets of a European wheel in the order they
+// sit around it, clockwise from 0
+var EuropeanWheelOrder = []int{
+	0, 32, 15, 19, 4, 21, 2, 25, 17, 34, 6, 27, 13, 36, 11, 30, 8, 23, 10,
+	5, 24, 16, 33, 1, 20, 14, 31, 9, 22, 18, 29, 7, 28, 12, 35, 3, 26,
+}
+
+// WheelType identifies the layout of the roulette wheel
+type WheelType int
+
+const (
+	// American wheels have 38 pockets: 1-36, 0 and 00
+	American WheelType = iota
+	// European wheels have 37 pockets: 1-36 and a single 0
+	European
+)
+
+// String returns the DSL name of the wheel type
+func (wt WheelType) String() string {
+	switch wt {
+	case American:
+		return "american"
+	case European:
+		return "european"
+	}
+	return fmt.Sprintf("WheelType(%d)", int(wt))
+}
+
+// ParseWheelType converts a DSL wheel name into a WheelType
+func ParseWheelType(name string) (WheelType, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "american":
+		return American, nil
+	case "european":
+		return European, nil
+	}
+	return 0, fmt.Errorf("unknown wheel type: %s", name)
+}
+
+// DoubleZero is the value used for the 00 pocket, since the integer literal
+// 00 is indistinguishable from 0
+const DoubleZero = -1
+
+// RouletteWheel represents the roulette wheel
+type RouletteWheel struct {
+	Numbers []int
+	rng     *rand.Rand
+}
+
+// NewRouletteWheel creates a new American roulette wheel
+func NewRouletteWheel() *RouletteWheel {
+	return NewWheel(American)
+}
+
+// NewSeededWheel creates a new American roulette wheel whose spins are fully
+// determined by seed
+func NewSeededWheel(seed int64) *RouletteWheel {
+	return newSeededWheel(American, seed)
+}
+
+// NewWheel creates a new roulette wheel of the given type
+func NewWheel(wheelType WheelType) *RouletteWheel {
+	return newSeededWheel(wheelType, newSeed())
+}
+
+// newSeededWheel creates a roulette wheel of the given type with its own
+// random source
+func newSeededWheel(wheelType WheelType, seed int64) *RouletteWheel {
+	numbers := make([]int, 36, 38)
+	for i := 0; i < 36; i++ {
+		numbers[i] = i + 1
+	}
+	numbers = append(numbers, 0) // Green 0
+	if wheelType == American {
+		numbers = append(numbers, DoubleZero) // Green 00
+	}
+	return &RouletteWheel{Numbers: numbers, rng: rand.New(rand.NewSource(seed))}
+}
+
+// newSeed returns a seed for runs that don't need to be reproducible
+func newSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// IsGreen reports whether n is one of the green pockets (0 or 00)
+func IsGreen(n int) bool {
+	return n == 0 || n == DoubleZero
+}
+
+// PocketLabel returns the label printed on the wheel for pocket n
+func PocketLabel(n int) string {
+	if n == DoubleZero {
+		return "00"
+	}
+	return strconv.Itoa(n)
+}
+
+// Spin spins the roulette wheel and returns the winning number
+func (rw *RouletteWheel) Spin() int {
+	return rw.Numbers[rw.rng.Intn(len(rw.Numbers))]
+}
+
+// redPockets is indexed by pocket number and marks the red pockets; the rest
+// of 1-36 are black. A table keeps the check to a single lookup per spin.
+var redPockets = func() (table [37]bool) {
+	for _, n := range []int{1, 3, 5, 7, 9, 12, 14, 16, 18, 19, 21, 23, 25, 27, 30, 32, 34, 36} {
+		table[n] = true
+	}
+	return table
+}()
+
+// IsRed reports whether n is a red pocket
+func IsRed(n int) bool {
+	return n >= 0 && n < len(redPockets) && redPockets[n]
+}
+
+// IsBlack reports whether n is a black pocket
+func IsBlack(n int) bool {
+	return n >= 1 && n <= 36 && !IsRed(n)
+}
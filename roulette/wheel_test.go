@@ -0,0 +1,91 @@
+package roulette
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSeededWheelGreenFrequency(t *testing.T) {
+	const spins = 380_000
+	wheel := NewSeededWheel(1)
+	counts := map[int]int{}
+	for i := 0; i < spins; i++ {
+		counts[wheel.Spin()]++
+	}
+	want := 1.0 / 38
+	for _, n := range []int{0, DoubleZero} {
+		got := float64(counts[n]) / spins
+		if math.Abs(got-want) > 0.002 {
+			t.Errorf("pocket %s came up %.4f of spins, want about %.4f", PocketLabel(n), got, want)
+		}
+	}
+}
+
+func TestGreensLoseOutsideBets(t *testing.T) {
+	// Covering both colours, or both parities, breaks even on every number
+	// but the greens, which lose both stakes
+	const spins = 10_000
+	wheel := NewSeededWheel(2)
+	greens := 0
+	for i := 0; i < spins; i++ {
+		if IsGreen(wheel.Spin()) {
+			greens++
+		}
+	}
+	for _, pair := range []string{"bet: red, 0, 1\nbet: black, 0, 1\n", "bet: even, 0, 1\nbet: odd, 0, 1\n"} {
+		strategy, err := ParseStrategy("bankroll: 100000\n" + pair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 100000 - 2*float64(greens)
+		if got := SimulateSeeded(strategy, spins, 2).FinalBankroll; got != want {
+			t.Errorf("%q over %d spins with %d greens left %v, want %v", pair, spins, greens, got, want)
+		}
+	}
+}
+
+func TestEuropeanWheelPockets(t *testing.T) {
+	wheel := NewWheel(European)
+	if len(wheel.Numbers) != 37 {
+		t.Fatalf("european wheel has %d pockets, want 37", len(wheel.Numbers))
+	}
+	for _, n := range wheel.Numbers {
+		if n == DoubleZero {
+			t.Fatal("european wheel has a 00 pocket")
+		}
+	}
+	if got := len(NewWheel(American).Numbers); got != 38 {
+		t.Errorf("american wheel has %d pockets, want 38", got)
+	}
+}
+
+func TestEuropeanEvenMoneyEdge(t *testing.T) {
+	strategy, err := ParseStrategy("bankroll: 10000000\nwheel: european\nbet: red, 0, 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strategy.Wheel != European {
+		t.Fatalf("wheel = %v, want european", strategy.Wheel)
+	}
+	edge := 100 * (SimulateSeeded(strategy, 1_000_000, 7).FinalBankroll - 10000000) / 1_000_000
+	if math.Abs(edge-(-100.0/37)) > 0.4 {
+		t.Errorf("even-money return was %.2f%%, want about -2.70%%", edge)
+	}
+}
+
+func TestSeededWheelsRepeat(t *testing.T) {
+	a, b, c := NewSeededWheel(99), NewSeededWheel(99), NewSeededWheel(100)
+	diverged := false
+	for i := 0; i < 1000; i++ {
+		x, y, z := a.Spin(), b.Spin(), c.Spin()
+		if x != y {
+			t.Fatalf("spin %d: wheels with the same seed gave %d and %d", i+1, x, y)
+		}
+		if x != z {
+			diverged = true
+		}
+	}
+	if !diverged {
+		t.Error("wheels with different seeds gave the same 1000 spins")
+	}
+}
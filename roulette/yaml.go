@@ -0,0 +1,205 @@
+package roulette
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseStrategyYAML reads a strategy from a YAML document. Its keys are the
+// DSL directives, and bets are given as a list:
+//
+//	bankroll: 1000
+//	wheel: european
+//	stop_loss: 500
+//	bets:
+//	  - {type: red, amount: 10}
+//	  - {type: split, value: [17, 20], amount: 5, group: a}
+//
+// Since a mapping can't repeat a key, phases are given as a list of their
+// own, each with a name, its bets and optionally an until condition and a
+// progression, while the top-level bets play in every phase:
+//
+//	phases:
+//	  - name: warmup
+//	    until: profit 50
+//	    bets: [{type: red, amount: 10}]
+//	  - name: chase
+//	    progression: martingale
+//	    bets: [{type: red, amount: 10}]
+//
+// Every directive and bet goes through the DSL parser, so the two formats
+// accept the same strategies. Errors are ParseErrors pointing at the line of
+// the YAML document.
+func ParseStrategyYAML(r io.Reader) (*Strategy, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML strategy: %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid YAML strategy: expected a mapping of directives")
+	}
+
+	// fail reports a problem with the document at the line of node
+	fail := func(node *yaml.Node, err error) error {
+		content := ""
+		if node.Line >= 1 && node.Line <= len(lines) {
+			content = strings.TrimSpace(lines[node.Line-1])
+		}
+		return &ParseError{Line: node.Line, Content: content, Reason: err.Error()}
+	}
+	p := &strategyParser{strategy: &Strategy{}}
+	root := doc.Content[0]
+	// As in the DSL, the wheel is read first so bets are checked against it
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value == "wheel" {
+			if err := p.parseLine("wheel: " + value.Value); err != nil {
+				return nil, fail(key, err)
+			}
+		}
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "bets":
+			if err := parseYAMLBets(p, key, value, fail); err != nil {
+				return nil, err
+			}
+		case "phases":
+			if err := parseYAMLPhases(p, key, value, fail); err != nil {
+				return nil, err
+			}
+		case "phase", "until":
+			return nil, fail(key, fmt.Errorf("%s can't be a top-level key in YAML: list the phases under phases", key.Value))
+		default:
+			token, err := yamlToken(value)
+			if err == nil {
+				err = p.parseLine(key.Value + ": " + token)
+			}
+			if err != nil {
+				return nil, fail(key, err)
+			}
+		}
+	}
+	return p.finish()
+}
+
+// parseYAMLBets reads the list of bets under key for the phase p is on
+func parseYAMLBets(p *strategyParser, key, value *yaml.Node, fail func(*yaml.Node, error) error) error {
+	if value.Kind != yaml.SequenceNode {
+		return fail(key, fmt.Errorf("bets must be a list"))
+	}
+	for _, item := range value.Content {
+		line, group, err := yamlBetLine(item)
+		if err == nil {
+			p.group = group
+			err = p.parseLine(line)
+		}
+		if err != nil {
+			return fail(item, err)
+		}
+	}
+	return nil
+}
+
+// parseYAMLPhases reads the phases list, handing each phase's name, until
+// condition and progression to the DSL parser as the lines a DSL phase would
+// have before reading its bets. The parser leaves the phases afterwards, so
+// top-level keys after the list still apply to the whole strategy.
+func parseYAMLPhases(p *strategyParser, key, value *yaml.Node, fail func(*yaml.Node, error) error) error {
+	if value.Kind != yaml.SequenceNode {
+		return fail(key, fmt.Errorf("phases must be a list"))
+	}
+	for _, item := range value.Content {
+		if item.Kind != yaml.MappingNode {
+			return fail(item, fmt.Errorf("each phase must be a mapping with a name and bets"))
+		}
+		fields := make(map[string]*yaml.Node)
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			switch name := item.Content[i].Value; name {
+			case "name", "until", "progression", "bets":
+				fields[name] = item.Content[i+1]
+			default:
+				return fail(item.Content[i], fmt.Errorf("unknown phase field: %s", name))
+			}
+		}
+		if fields["name"] == nil {
+			return fail(item, fmt.Errorf("phase name is missing"))
+		}
+		for _, name := range []string{"name", "until", "progression"} {
+			node := fields[name]
+			if node == nil {
+				continue
+			}
+			directive := name
+			if name == "name" {
+				directive = "phase"
+			}
+			token, err := yamlToken(node)
+			if err == nil {
+				err = p.parseLine(directive + ": " + token)
+			}
+			if err != nil {
+				return fail(node, err)
+			}
+		}
+		if bets := fields["bets"]; bets != nil {
+			if err := parseYAMLBets(p, bets, bets, fail); err != nil {
+				return err
+			}
+		}
+	}
+	p.phase = 0
+	return nil
+}
+
+// yamlBetLine turns one entry of the bets list into a DSL bet line and the
+// group it belongs to
+func yamlBetLine(node *yaml.Node) (string, string, error) {
+	if node.Kind != yaml.MappingNode {
+		return "", "", fmt.Errorf("each bet must be a mapping with a type and an amount")
+	}
+	fields := map[string]string{"value": "0"}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		switch key {
+		case "type", "value", "amount", "group":
+		default:
+			return "", "", fmt.Errorf("unknown bet field: %s", key)
+		}
+		token, err := yamlToken(node.Content[i+1])
+		if err != nil {
+			return "", "", err
+		}
+		fields[key] = token
+	}
+	line := fmt.Sprintf("bet: %s, %s, %s", fields["type"], fields["value"], fields["amount"])
+	return line, fields["group"], nil
+}
+
+// yamlToken turns a YAML value into the text a DSL line would hold in its
+// place, joining lists with spaces
+func yamlToken(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, nil
+	case yaml.SequenceNode:
+		tokens := make([]string, len(node.Content))
+		for i, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("lists may only hold plain values")
+			}
+			tokens[i] = item.Value
+		}
+		return strings.Join(tokens, " "), nil
+	}
+	return "", fmt.Errorf("expected a value or a list of values")
+}
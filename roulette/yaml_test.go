@@ -0,0 +1,104 @@
+package roulette
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStrategyYAMLMatchesDSL(t *testing.T) {
+	yamlStrategy, err := ParseStrategyYAML(strings.NewReader(`
+bankroll: 1000
+wheel: european
+progression: dalembert
+unit: 5
+stop_loss: 500
+take_profit: 1500
+schedule: a b
+bets:
+  - {type: red, amount: 10, group: a}
+  - {type: split, value: [17, 20], amount: 5, group: b}
+  - {type: number, value: 0, amount: 2%}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dslStrategy := mustParse(t, `bankroll: 1000
+wheel: european
+progression: dalembert
+unit: 5
+stop_loss: 500
+take_profit: 1500
+schedule: a b
+group: a
+bet: red, 0, 10
+group: b
+bet: split, 17 20, 5
+group:
+bet: number, 0, 2%
+`)
+	if !reflect.DeepEqual(yamlStrategy, dslStrategy) {
+		t.Errorf("YAML strategy = %+v\nwant the DSL's %+v", yamlStrategy, dslStrategy)
+	}
+}
+
+func TestParseStrategyYAMLErrors(t *testing.T) {
+	_, err := ParseStrategyYAML(strings.NewReader("bankroll: 100\nbets:\n  - {type: red, amount: 10}\n  - {type: dozen, value: 4, amount: 10}\n"))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 4 || !strings.Contains(parseErr.Reason, "invalid dozen 4") {
+		t.Errorf("got error %v, want invalid dozen 4 on line 4", err)
+	}
+
+	_, err = ParseStrategyYAML(strings.NewReader("bankroll: 100\nstop_los: 50\nbets: [{type: red, amount: 10}]\n"))
+	if !errors.As(err, &parseErr) || parseErr.Line != 2 {
+		t.Errorf("got error %v, want one on line 2", err)
+	}
+
+	for _, doc := range []string{"bankroll: [100\n", "- just\n- a list\n", "bankroll: 100\nbets: {type: red}\n"} {
+		if _, err := ParseStrategyYAML(strings.NewReader(doc)); err == nil {
+			t.Errorf("%q was accepted", doc)
+		}
+	}
+}
+
+func TestParseStrategyYAMLPhases(t *testing.T) {
+	yamlStrategy, err := ParseStrategyYAML(strings.NewReader(`
+bankroll: 1000
+bets:
+  - {type: number, value: 17, amount: 1}
+phases:
+  - name: warmup
+    until: profit 50
+    bets: [{type: red, amount: 10}]
+  - name: chase
+    progression: martingale
+    bets:
+      - {type: black, amount: 10}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dslStrategy := mustParse(t, `bankroll: 1000
+bet: number, 17, 1
+phase: warmup
+bet: red, 0, 10
+until: profit 50
+phase: chase
+progression: martingale
+bet: black, 0, 10
+`)
+	if !reflect.DeepEqual(yamlStrategy, dslStrategy) {
+		t.Errorf("YAML strategy = %+v\nwant the DSL's %+v", yamlStrategy, dslStrategy)
+	}
+
+	for _, doc := range []string{
+		"bankroll: 100\nphase: warmup\nbets: [{type: red, amount: 10}]\n",
+		"bankroll: 100\nuntil: profit 5\nbets: [{type: red, amount: 10}]\n",
+		"bankroll: 100\nphases:\n  - name: empty\n",
+	} {
+		if _, err := ParseStrategyYAML(strings.NewReader(doc)); err == nil {
+			t.Errorf("%q was accepted", doc)
+		}
+	}
+}